@@ -0,0 +1,58 @@
+package caches
+
+import (
+	"github.com/wujiu2020/strip/utils"
+)
+
+// BatchProvider is optional capability of CacheProvider to access keys in one round-trip
+type BatchProvider interface {
+	GetMulti(keys []string) (map[string]utils.StrTo, error)      // get cached values of keys, missed keys are absent
+	SetMulti(values map[string]interface{}, params ...int) error // set cached values with optional timeout seconds
+	DeleteMulti(keys []string) error                             // delete cached values, missed keys are ignored
+}
+
+// Batch returns BatchProvider of provider, calls single operations in a loop
+// if the provider doesn't support it
+func Batch(provider CacheProvider) BatchProvider {
+	if batch, ok := provider.(BatchProvider); ok {
+		return batch
+	}
+	return batchLoop{provider}
+}
+
+type batchLoop struct {
+	CacheProvider
+}
+
+func (b batchLoop) GetMulti(keys []string) (map[string]utils.StrTo, error) {
+	values := make(map[string]utils.StrTo, len(keys))
+	for _, key := range keys {
+		value, err := b.Get(key)
+		switch err {
+		case nil:
+			values[key] = value
+		case ErrMissedKey:
+		default:
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (b batchLoop) SetMulti(values map[string]interface{}, params ...int) error {
+	for key, value := range values {
+		if err := b.Set(key, value, params...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b batchLoop) DeleteMulti(keys []string) error {
+	for _, key := range keys {
+		if err := b.Delete(key); err != nil && err != ErrMissedKey {
+			return err
+		}
+	}
+	return nil
+}
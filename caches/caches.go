@@ -12,7 +12,8 @@ const (
 )
 
 var (
-	ErrMissedKey = errors.New("err_missed_key")
+	ErrMissedKey   = errors.New("err_missed_key")
+	ErrUnsupported = errors.New("unsupported by provider")
 )
 
 type CacheProvider interface {
@@ -27,6 +28,46 @@ type CacheProvider interface {
 	GC() error                                              // use for interval GC
 }
 
+// NXProvider is optional capability of CacheProvider to set key only if not exists
+type NXProvider interface {
+	SetNX(key string, value interface{}, params ...int) (bool, error) // set cached key if not exists, reports whether it's set
+}
+
+// CounterProvider is optional capability of CacheProvider to incr and get the result in one operation,
+// missed key returns ErrMissedKey
+type CounterProvider interface {
+	IncrBy(key string, delta int64) (int64, error) // incr integer value, returns the new value
+	DecrBy(key string, delta int64) (int64, error) // decr integer value, returns the new value
+}
+
+// ScanProvider is optional capability of CacheProvider to iterate cached keys,
+// keys are without KeyPrefix of the provider
+type ScanProvider interface {
+	Keys(prefix string) ([]string, error)                // get cached keys start with prefix
+	Scan(prefix string, fn func(key string) error) error // iterate cached keys start with prefix, stops at the first error of fn
+}
+
+// CASProvider is optional capability of CacheProvider to compare and swap value
+type CASProvider interface {
+	CAS(key string, old, new interface{}, params ...int) (bool, error) // set new value with optional timeout seconds if current value equals old, reports whether it's swapped
+}
+
+// TTLProvider is optional capability of CacheProvider to get remaining timeout of key
+type TTLProvider interface {
+	TTL(key string) (time.Duration, error) // remaining timeout of cached key, negative if never expire
+}
+
+// TTLValue is cached value with its remaining timeout, negative if never expire
+type TTLValue struct {
+	Value utils.StrTo
+	TTL   time.Duration
+}
+
+// BatchTTLProvider is optional capability of CacheProvider to get values with their remaining timeout in one round-trip
+type BatchTTLProvider interface {
+	GetMultiTTL(keys []string) (map[string]TTLValue, error) // get cached values and timeouts of keys, missed keys are absent
+}
+
 func getTimeoutDur(params ...int) time.Duration {
 	var timeout time.Duration
 	if len(params) > 0 {
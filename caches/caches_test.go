@@ -1,8 +1,10 @@
 package caches
 
 import (
+	"errors"
 	"log"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/mediocregopher/radix.v2/pool"
+	"github.com/mediocregopher/radix.v2/redis"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -65,7 +68,19 @@ func initRedisProvider() {
 	}
 }
 
+func initMemoryProvider() {
+	var err error
+	cache, err = NewMemoryProvider(MemoryConfig{})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
 func TestMain(m *testing.M) {
+	initMemoryProvider()
+	if code := m.Run(); code != 0 {
+		os.Exit(code)
+	}
 	initMgoProvider()
 	if code := m.Run(); code != 0 {
 		os.Exit(code)
@@ -206,3 +221,104 @@ func Test_CacheMgoKeyExpired(t *testing.T) {
 	has, _ := cache.Has("key")
 	assert.False(t, has)
 }
+
+func Test_CacheMemoryExpired(t *testing.T) {
+	if _, ok := cache.(*memoryProvide); !ok {
+		t.Skip()
+	}
+
+	prov, _ := NewMemoryProvider(MemoryConfig{})
+	mc := prov.(*memoryProvide)
+	now := time.Now()
+	mc.now = func() time.Time { return now }
+
+	mc.Set("default", "val")
+	mc.Set("short", "val", 10)
+	mc.Set("never", "val", -1)
+	mc.Set("num", 1, 10)
+
+	now = now.Add(9 * time.Second)
+	assert.NoError(t, mc.Incr("num", 5))
+	value, _ := mc.Get("num")
+	assert.Equal(t, 6, value.MustInt())
+
+	now = now.Add(time.Second)
+	has, _ := mc.Has("short")
+	assert.False(t, has)
+	// timeout kept by incr
+	_, err := mc.Get("num")
+	assert.Equal(t, ErrMissedKey, err)
+	assert.Equal(t, ErrMissedKey, mc.Incr("num"))
+
+	assert.NoError(t, mc.Touch("default", 100))
+	now = now.Add(DefaultTimeout)
+	assert.NoError(t, mc.GC())
+	assert.Equal(t, 2, mc.ll.Len())
+	has, _ = mc.Has("never")
+	assert.True(t, has)
+	has, _ = mc.Has("default")
+	assert.True(t, has)
+
+	mc.Set("text", "abc")
+	assert.Error(t, mc.Incr("text"))
+}
+
+func Test_CacheMemoryLRU(t *testing.T) {
+	if _, ok := cache.(*memoryProvide); !ok {
+		t.Skip()
+	}
+
+	prov, _ := NewMemoryProvider(MemoryConfig{MaxEntries: 3, MaxBytes: 20})
+	mc := prov.(*memoryProvide)
+
+	mc.Set("a", "1")
+	mc.Set("b", "2")
+	mc.Set("c", "3")
+	mc.Get("a")
+	mc.Set("d", "4") // evicts b
+
+	has, _ := mc.Has("b")
+	assert.False(t, has)
+	for _, key := range []string{"a", "c", "d"} {
+		has, _ = mc.Has(key)
+		assert.True(t, has, key)
+	}
+
+	mc.Set("e", "0123456789abcdef") // 17 bytes, evicts a and c
+	assert.Equal(t, int64(19), mc.bytes)
+	has, _ = mc.Has("d")
+	assert.True(t, has)
+	has, _ = mc.Has("a")
+	assert.False(t, has)
+	has, _ = mc.Has("c")
+	assert.False(t, has)
+
+	mc.Set("e", "0")
+	assert.Equal(t, int64(4), mc.bytes)
+
+	// concurrent incr
+	mc.Set("num", 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mc.Incr("num")
+		}()
+	}
+	wg.Wait()
+	value, _ := mc.Get("num")
+	assert.Equal(t, 50, value.MustInt())
+}
+
+func Test_GlobEscape(t *testing.T) {
+	assert.Equal(t, `prefix:`, globEscape("prefix:"))
+	assert.Equal(t, `a\*b\?\[c\]\\`, globEscape(`a*b?[c]\`))
+}
+
+func Test_IsUnknownCommand(t *testing.T) {
+	assert.True(t, isUnknownCommand(redis.NewResp(errors.New("ERR unknown command 'unlink'"))))
+	assert.False(t, isUnknownCommand(redis.NewResp(errors.New("WRONGTYPE Operation against a key"))))
+	assert.False(t, isUnknownCommand(redis.NewRespIOErr(errors.New("i/o timeout"))))
+	assert.False(t, isUnknownCommand(redis.NewResp(1)))
+}
@@ -0,0 +1,402 @@
+// Package cachestest provides a conformance suite for caches.CacheProvider
+// implementations, and in-process memcache and redis servers to run it offline.
+package cachestest
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip/caches"
+)
+
+// Factory returns a provider whose keys are isolated by scope,
+// providers of different scopes may share one backend
+type Factory func(t *testing.T, scope string) caches.CacheProvider
+
+type Option struct {
+	// Clean removes keys of all scopes, eg: memcache flush_all
+	UnscopedClean bool
+}
+
+var scopeSeq int64
+
+// RunConformance runs the shared test suite of CacheProvider semantics:
+//
+//	Get, Touch and Delete of missing key return caches.ErrMissedKey
+//	values are stored by their string form
+//	positive timeout expires in seconds, negative never expires
+//	Incr and Decr of non-integer value fail, of missing key either
+//	return caches.ErrMissedKey or create the key
+//	GetMulti, SetMulti and DeleteMulti of caches.Batch
+//	IncrBy, SetNX, CAS, Keys, Scan, TTL and GetMultiTTL of the providers implement them
+//	Clean removes keys of its own scope only, unless UnscopedClean
+func RunConformance(t *testing.T, factory Factory, opts ...Option) {
+	var opt Option
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	newScope := func() string {
+		return "cachestest" + strconv.FormatInt(atomic.AddInt64(&scopeSeq, 1), 10) + ":"
+	}
+	run := func(name string, f func(t *testing.T, cache caches.CacheProvider)) {
+		t.Run(name, func(t *testing.T) {
+			f(t, factory(t, newScope()))
+		})
+	}
+
+	run("MissedKey", func(t *testing.T, cache caches.CacheProvider) {
+		_, err := cache.Get("missing")
+		assert.Equal(t, caches.ErrMissedKey, err, "Get")
+		assert.Equal(t, caches.ErrMissedKey, cache.Touch("missing", 10), "Touch")
+		assert.Equal(t, caches.ErrMissedKey, cache.Delete("missing"), "Delete")
+
+		has, err := cache.Has("missing")
+		assert.NoError(t, err)
+		assert.False(t, has)
+	})
+
+	run("SetGetDelete", func(t *testing.T, cache caches.CacheProvider) {
+		assert.NoError(t, cache.Set("key", "value"))
+		v, err := cache.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, "value", v.String())
+
+		has, err := cache.Has("key")
+		assert.NoError(t, err)
+		assert.True(t, has)
+
+		assert.NoError(t, cache.Set("key", 10))
+		v, err = cache.Get("key")
+		assert.NoError(t, err)
+		n, err := v.Int()
+		assert.NoError(t, err)
+		assert.Equal(t, 10, n)
+
+		assert.NoError(t, cache.Delete("key"))
+		_, err = cache.Get("key")
+		assert.Equal(t, caches.ErrMissedKey, err)
+		assert.Equal(t, caches.ErrMissedKey, cache.Delete("key"))
+	})
+
+	run("Expired", func(t *testing.T, cache caches.CacheProvider) {
+		assert.NoError(t, cache.Set("short", "v", 1))
+		assert.NoError(t, cache.Set("touched", "v", 1))
+		assert.NoError(t, cache.Set("never", "v", -1))
+		assert.NoError(t, cache.Set("default", "v"))
+		assert.NoError(t, cache.Touch("touched", 10))
+
+		has, err := cache.Has("short")
+		assert.NoError(t, err)
+		assert.True(t, has)
+
+		// second resolution backends expire on the next second at most
+		time.Sleep(2100 * time.Millisecond)
+		assert.NoError(t, cache.GC())
+
+		_, err = cache.Get("short")
+		assert.Equal(t, caches.ErrMissedKey, err, "short")
+		has, err = cache.Has("short")
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		for _, key := range []string{"touched", "never", "default"} {
+			_, err = cache.Get(key)
+			assert.NoError(t, err, key)
+		}
+	})
+
+	run("IncrDecr", func(t *testing.T, cache caches.CacheProvider) {
+		assert.NoError(t, cache.Set("num", 1))
+		assert.NoError(t, cache.Incr("num"))
+		assert.NoError(t, cache.Incr("num", 5))
+		assert.NoError(t, cache.Decr("num", 2))
+		assert.NoError(t, cache.Decr("num"))
+		v, err := cache.Get("num")
+		assert.NoError(t, err)
+		assert.Equal(t, "4", v.String())
+
+		assert.NoError(t, cache.Set("text", "abc"))
+		assert.Error(t, cache.Incr("text"), "Incr non-integer")
+		assert.Error(t, cache.Decr("text"), "Decr non-integer")
+		v, err = cache.Get("text")
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", v.String())
+
+		err = cache.Incr("missing")
+		if err != nil {
+			assert.Equal(t, caches.ErrMissedKey, err, "Incr missing")
+		} else {
+			v, err = cache.Get("missing")
+			assert.NoError(t, err)
+			assert.Equal(t, "1", v.String(), "Incr missing creates 1")
+		}
+	})
+
+	run("IncrConcurrent", func(t *testing.T, cache caches.CacheProvider) {
+		assert.NoError(t, cache.Set("num", 0))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, cache.Incr("num"))
+			}()
+		}
+		wg.Wait()
+
+		v, err := cache.Get("num")
+		assert.NoError(t, err)
+		assert.Equal(t, "20", v.String())
+	})
+
+	run("Batch", func(t *testing.T, cache caches.CacheProvider) {
+		batch := caches.Batch(cache)
+
+		assert.NoError(t, batch.SetMulti(map[string]interface{}{"a": 1, "b": "b"}, 10))
+		values, err := batch.GetMulti([]string{"a", "b", "missing"})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(values))
+		assert.Equal(t, "1", values["a"].String())
+		assert.Equal(t, "b", values["b"].String())
+
+		values, err = batch.GetMulti(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(values))
+
+		assert.NoError(t, batch.DeleteMulti([]string{"a", "missing"}))
+		_, err = cache.Get("a")
+		assert.Equal(t, caches.ErrMissedKey, err)
+		has, err := cache.Has("b")
+		assert.NoError(t, err)
+		assert.True(t, has)
+	})
+
+	run("Counter", func(t *testing.T, cache caches.CacheProvider) {
+		counter, ok := cache.(caches.CounterProvider)
+		if !ok {
+			t.Skip("CounterProvider is not implemented")
+		}
+
+		assert.NoError(t, cache.Set("num", 10))
+		n, err := counter.IncrBy("num", 5)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(15), n)
+		n, err = counter.DecrBy("num", 3)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(12), n)
+		n, err = counter.IncrBy("num", -2)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), n)
+
+		assert.NoError(t, cache.Set("text", "abc"))
+		_, err = counter.IncrBy("text", 1)
+		assert.Error(t, err)
+
+		_, err = counter.IncrBy("missing", 2)
+		assert.Equal(t, caches.ErrMissedKey, err, "IncrBy missing")
+		_, err = counter.DecrBy("missing", 2)
+		assert.Equal(t, caches.ErrMissedKey, err, "DecrBy missing")
+		_, err = cache.Get("missing")
+		assert.Equal(t, caches.ErrMissedKey, err, "missing is not created")
+	})
+
+	run("SetNX", func(t *testing.T, cache caches.CacheProvider) {
+		nx, ok := cache.(caches.NXProvider)
+		if !ok {
+			t.Skip("NXProvider is not implemented")
+		}
+
+		set, err := nx.SetNX("key", "a", 10)
+		assert.NoError(t, err)
+		assert.True(t, set)
+		set, err = nx.SetNX("key", "b", 10)
+		assert.NoError(t, err)
+		assert.False(t, set)
+
+		v, err := cache.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", v.String())
+
+		// only one of concurrent callers wins
+		var (
+			wg   sync.WaitGroup
+			wins int32
+		)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if set, _ := nx.SetNX("race", 1, 10); set {
+					atomic.AddInt32(&wins, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(1), wins)
+	})
+
+	run("CAS", func(t *testing.T, cache caches.CacheProvider) {
+		cas, ok := cache.(caches.CASProvider)
+		if !ok {
+			t.Skip("CASProvider is not implemented")
+		}
+
+		_, err := cas.CAS("missing", "a", "b", 10)
+		assert.Equal(t, caches.ErrMissedKey, err)
+
+		assert.NoError(t, cache.Set("key", "a"))
+		swapped, err := cas.CAS("key", "b", "c", 10)
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+
+		swapped, err = cas.CAS("key", "a", "c", 10)
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+		v, err := cache.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, "c", v.String())
+
+		// increments by CAS loops are not lost
+		assert.NoError(t, cache.Set("num", 0))
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					v, err := cache.Get("num")
+					if !assert.NoError(t, err) {
+						return
+					}
+					n, _ := v.Int()
+					if swapped, err := cas.CAS("num", n, n+1, 10); err != nil || swapped {
+						assert.NoError(t, err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		v, err = cache.Get("num")
+		assert.NoError(t, err)
+		assert.Equal(t, "10", v.String())
+	})
+
+	run("Scan", func(t *testing.T, cache caches.CacheProvider) {
+		scan, ok := cache.(caches.ScanProvider)
+		if !ok {
+			t.Skip("ScanProvider is not implemented")
+		}
+
+		for i := 0; i < 30; i++ {
+			assert.NoError(t, cache.Set("user:"+strconv.Itoa(i), i))
+		}
+		assert.NoError(t, cache.Set("users", "x"))
+		assert.NoError(t, cache.Set("other:1", "x"))
+		assert.NoError(t, cache.Set("user:expired", "x", 1))
+		assert.NoError(t, cache.Delete("user:expired"))
+
+		keys, err := scan.Keys("user:")
+		assert.NoError(t, err)
+		sort.Strings(keys)
+		keys = uniqueKeys(keys)
+		assert.Equal(t, 30, len(keys))
+		for _, key := range keys {
+			assert.True(t, strings.HasPrefix(key, "user:"), key)
+		}
+
+		// deletes keys while scanning
+		assert.NoError(t, scan.Scan("user:", func(key string) error {
+			cache.Delete(key)
+			return nil
+		}))
+		keys, err = scan.Keys("user")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"users"}, keys)
+
+		errStop := errors.New("stop")
+		assert.Equal(t, errStop, scan.Scan("", func(key string) error {
+			return errStop
+		}))
+	})
+
+	run("TTL", func(t *testing.T, cache caches.CacheProvider) {
+		ttl, ok := cache.(caches.TTLProvider)
+		if !ok {
+			t.Skip("TTLProvider is not implemented")
+		}
+
+		_, err := ttl.TTL("missing")
+		assert.Equal(t, caches.ErrMissedKey, err)
+
+		assert.NoError(t, cache.Set("expiring", 1, 10))
+		d, err := ttl.TTL("expiring")
+		assert.NoError(t, err)
+		assert.True(t, d > 9*time.Second && d <= 10*time.Second, "ttl %s", d)
+
+		assert.NoError(t, cache.Set("forever", 1, -1))
+		d, err = ttl.TTL("forever")
+		assert.NoError(t, err)
+		assert.True(t, d < 0, "ttl %s", d)
+
+		batch, ok := cache.(caches.BatchTTLProvider)
+		if !ok {
+			return
+		}
+		values, err := batch.GetMultiTTL([]string{"expiring", "forever", "missing"})
+		assert.NoError(t, err)
+		assert.Len(t, values, 2)
+		assert.Equal(t, "1", values["expiring"].Value.String())
+		assert.True(t, values["expiring"].TTL > 9*time.Second, "ttl %s", values["expiring"].TTL)
+		assert.True(t, values["forever"].TTL < 0, "ttl %s", values["forever"].TTL)
+	})
+
+	t.Run("Clean", func(t *testing.T) {
+		a := factory(t, newScope())
+		b := factory(t, newScope())
+
+		for i := 0; i < 30; i++ {
+			assert.NoError(t, a.Set("key"+strconv.Itoa(i), "a"))
+		}
+		assert.NoError(t, a.Set("key", "a"))
+		assert.NoError(t, b.Set("key", "b"))
+
+		v, err := a.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, "a", v.String(), "scopes are isolated")
+
+		assert.NoError(t, a.Clean())
+		_, err = a.Get("key")
+		assert.Equal(t, caches.ErrMissedKey, err)
+		_, err = a.Get("key29")
+		assert.Equal(t, caches.ErrMissedKey, err)
+
+		v, err = b.Get("key")
+		if opt.UnscopedClean {
+			assert.Equal(t, caches.ErrMissedKey, err)
+		} else {
+			assert.NoError(t, err, "keys of other scope are kept")
+			assert.Equal(t, "b", v.String())
+		}
+	})
+}
+
+// uniqueKeys removes duplicated keys of sorted keys, Scan may return a key more than once
+func uniqueKeys(keys []string) []string {
+	var unique []string
+	for i, key := range keys {
+		if i == 0 || key != keys[i-1] {
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
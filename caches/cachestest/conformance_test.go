@@ -0,0 +1,158 @@
+package cachestest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/mediocregopher/radix.v2/pool"
+	"github.com/wujiu2020/strip/caches"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	RunConformance(t, func(t *testing.T, scope string) caches.CacheProvider {
+		cache, err := caches.NewMemoryProvider(caches.MemoryConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cache
+	})
+}
+
+// singleProvider hides optional capabilities of the provider
+type singleProvider struct {
+	caches.CacheProvider
+}
+
+func TestSingleConformance(t *testing.T) {
+	RunConformance(t, func(t *testing.T, scope string) caches.CacheProvider {
+		cache, err := caches.NewMemoryProvider(caches.MemoryConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return singleProvider{cache}
+	})
+}
+
+func TestMemcacheConformance(t *testing.T) {
+	srv, err := NewMemcacheServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client := memcache.New(srv.Addr())
+	RunConformance(t, func(t *testing.T, scope string) caches.CacheProvider {
+		cache, err := caches.NewMcProvider(caches.McConfig{KeyPrefix: scope, Client: client})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cache
+	}, Option{UnscopedClean: true})
+}
+
+func TestRedisConformance(t *testing.T) {
+	srv, err := NewRedisServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := pool.New("tcp", srv.Addr(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Empty()
+
+	RunConformance(t, func(t *testing.T, scope string) caches.CacheProvider {
+		cache, err := caches.NewRedisProvider(caches.RedisConfig{KeyPrefix: scope, Client: client, ScanCount: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cache
+	})
+}
+
+func TestTieredConformance(t *testing.T) {
+	srv, err := NewRedisServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := pool.New("tcp", srv.Addr(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Empty()
+
+	notifier, _ := caches.NewRedisNotifier(caches.RedisNotifierConfig{Client: client})
+	RunConformance(t, func(t *testing.T, scope string) caches.CacheProvider {
+		l2, err := caches.NewRedisProvider(caches.RedisConfig{KeyPrefix: scope, Client: client, ScanCount: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache, err := caches.NewTiered(caches.TieredConfig{L2: l2, Notifier: notifier})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cache
+	})
+}
+
+func TestRedisNotifier(t *testing.T) {
+	srv, err := NewRedisServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	client, err := pool.New("tcp", srv.Addr(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Empty()
+
+	notifier, _ := caches.NewRedisNotifier(caches.RedisNotifierConfig{Client: client})
+	received := make(chan caches.Invalidation, 1)
+	cancel, err := notifier.Subscribe(func(inv caches.Invalidation) {
+		received <- inv
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+
+	if err := notifier.Publish(caches.Invalidation{Origin: "a", Keys: []string{"k1", "k2"}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case inv := <-received:
+		if inv.Origin != "a" || len(inv.Keys) != 2 || inv.Keys[1] != "k2" || inv.All {
+			t.Fatalf("received %+v", inv)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("invalidation is not received")
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		match      bool
+	}{
+		{"*", "", true},
+		{"prefix:*", "prefix:a", true},
+		{"prefix:*", "other:a", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+		{"*b*", "abc", true},
+	}
+	for _, c := range cases {
+		if globMatch(c.pattern, c.s) != c.match {
+			t.Errorf("globMatch(%q, %q) should be %v", c.pattern, c.s, c.match)
+		}
+	}
+}
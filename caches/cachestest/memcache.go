@@ -0,0 +1,215 @@
+package cachestest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memcache treats expiration larger than 30 days as unix time
+const relativeExpirationLimit = 60 * 60 * 24 * 30
+
+type mcItem struct {
+	value     []byte
+	flags     uint32
+	cas       uint64
+	expiredAt time.Time
+}
+
+// MemcacheServer is an in-process server of memcache text protocol,
+// supports get, gets, set, add, replace, cas, delete, incr, decr, touch,
+// flush_all and version
+type MemcacheServer struct {
+	*server
+
+	mu    sync.Mutex
+	items map[string]*mcItem
+	cas   uint64
+}
+
+// NewMemcacheServer starts server listens on random local port
+func NewMemcacheServer() (*MemcacheServer, error) {
+	s := &MemcacheServer{items: make(map[string]*mcItem)}
+	srv, err := newServer(s.serve)
+	if err != nil {
+		return nil, err
+	}
+	s.server = srv
+	return s, nil
+}
+
+func (s *MemcacheServer) serve(conn net.Conn) {
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+
+		if err := s.handle(rw, args); err != nil {
+			return
+		}
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *MemcacheServer) handle(rw *bufio.ReadWriter, args []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmd := args[0]
+	switch cmd {
+	case "get", "gets":
+		for _, key := range args[1:] {
+			item := s.get(key)
+			if item == nil {
+				continue
+			}
+			if cmd == "gets" {
+				fmt.Fprintf(rw, "VALUE %s %d %d %d\r\n", key, item.flags, len(item.value), item.cas)
+			} else {
+				fmt.Fprintf(rw, "VALUE %s %d %d\r\n", key, item.flags, len(item.value))
+			}
+			rw.Write(item.value)
+			rw.WriteString("\r\n")
+		}
+		rw.WriteString("END\r\n")
+
+	case "set", "add", "replace", "cas":
+		if len(args) < 5 || cmd == "cas" && len(args) < 6 {
+			rw.WriteString("ERROR\r\n")
+			return nil
+		}
+		flags, _ := strconv.ParseUint(args[2], 10, 32)
+		exp, _ := strconv.ParseInt(args[3], 10, 64)
+		size, err := strconv.Atoi(args[4])
+		if err != nil || size < 0 {
+			rw.WriteString("CLIENT_ERROR bad data chunk\r\n")
+			return nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(rw, data); err != nil {
+			return err
+		}
+
+		old := s.get(args[1])
+		switch {
+		case cmd == "add" && old != nil:
+			rw.WriteString("NOT_STORED\r\n")
+			return nil
+		case cmd == "replace" && old == nil:
+			rw.WriteString("NOT_STORED\r\n")
+			return nil
+		case cmd == "cas" && old == nil:
+			rw.WriteString("NOT_FOUND\r\n")
+			return nil
+		case cmd == "cas" && strconv.FormatUint(old.cas, 10) != args[5]:
+			rw.WriteString("EXISTS\r\n")
+			return nil
+		}
+
+		s.cas++
+		s.items[args[1]] = &mcItem{
+			value:     data[:size],
+			flags:     uint32(flags),
+			cas:       s.cas,
+			expiredAt: expiredAt(exp),
+		}
+		rw.WriteString("STORED\r\n")
+
+	case "delete":
+		if s.get(args[1]) == nil {
+			rw.WriteString("NOT_FOUND\r\n")
+			return nil
+		}
+		delete(s.items, args[1])
+		rw.WriteString("DELETED\r\n")
+
+	case "incr", "decr":
+		item := s.get(args[1])
+		if item == nil {
+			rw.WriteString("NOT_FOUND\r\n")
+			return nil
+		}
+		delta, err := strconv.ParseUint(args[2], 10, 64)
+		if err != nil {
+			rw.WriteString("CLIENT_ERROR invalid numeric delta argument\r\n")
+			return nil
+		}
+		n, err := strconv.ParseUint(string(item.value), 10, 64)
+		if err != nil {
+			rw.WriteString("CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+			return nil
+		}
+		if cmd == "incr" {
+			n += delta
+		} else if delta > n {
+			// capped at zero
+			n = 0
+		} else {
+			n -= delta
+		}
+		s.cas++
+		item.value = []byte(strconv.FormatUint(n, 10))
+		item.cas = s.cas
+		fmt.Fprintf(rw, "%d\r\n", n)
+
+	case "touch":
+		item := s.get(args[1])
+		if item == nil {
+			rw.WriteString("NOT_FOUND\r\n")
+			return nil
+		}
+		exp, _ := strconv.ParseInt(args[2], 10, 64)
+		item.expiredAt = expiredAt(exp)
+		rw.WriteString("TOUCHED\r\n")
+
+	case "flush_all":
+		s.items = make(map[string]*mcItem)
+		rw.WriteString("OK\r\n")
+
+	case "version":
+		rw.WriteString("VERSION cachestest\r\n")
+
+	default:
+		rw.WriteString("ERROR\r\n")
+	}
+	return nil
+}
+
+// get returns alive item, expired one is removed
+func (s *MemcacheServer) get(key string) *mcItem {
+	item, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	if !item.expiredAt.IsZero() && !time.Now().Before(item.expiredAt) {
+		delete(s.items, key)
+		return nil
+	}
+	return item
+}
+
+func expiredAt(exp int64) time.Time {
+	switch {
+	case exp == 0:
+		return time.Time{}
+	case exp < 0:
+		// expired immediately
+		return time.Now()
+	case exp > relativeExpirationLimit:
+		return time.Unix(exp, 0)
+	}
+	return time.Now().Add(time.Duration(exp) * time.Second)
+}
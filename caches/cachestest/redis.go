@@ -0,0 +1,856 @@
+package cachestest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errProtocol = errors.New("protocol error")
+
+type redisItem struct {
+	value     string
+	expiredAt time.Time // zero if never expire
+	seq       uint64    // order of creation, the cursor of SCAN
+}
+
+// RedisServer is an in-process server of redis RESP protocol with string keys only,
+// supports PING, SELECT, GET, SET, SETNX, MGET, DEL, UNLINK, EXISTS, EXPIRE, PEXPIRE,
+// PERSIST, TTL, PTTL, INCR, DECR, INCRBY, DECRBY, KEYS, SCAN, FLUSHDB, FLUSHALL,
+// transactions of WATCH, UNWATCH, MULTI, EXEC and DISCARD,
+// pub/sub of SUBSCRIBE, UNSUBSCRIBE and PUBLISH,
+// and EVAL of scripts emulated by RegisterScript
+type RedisServer struct {
+	*server
+
+	mu       sync.Mutex
+	items    map[string]*redisItem
+	versions map[string]uint64 // bumped by writes of key, checked by WATCH
+	epoch    uint64            // bumped by flush
+	seq      uint64
+	scripts  map[string]RedisScript
+
+	subscribers map[string]map[*redisConn]bool
+}
+
+// NewRedisServer starts server listens on random local port
+func NewRedisServer() (*RedisServer, error) {
+	s := &RedisServer{
+		items:    make(map[string]*redisItem),
+		versions: make(map[string]uint64),
+		scripts:  make(map[string]RedisScript),
+
+		subscribers: make(map[string]map[*redisConn]bool),
+	}
+	srv, err := newServer(s.serve)
+	if err != nil {
+		return nil, err
+	}
+	s.server = srv
+	s.scripts[redisIncrByScript] = redisIncrBy
+	return s, nil
+}
+
+// redisIncrByScript is the script of IncrBy of caches redis provider,
+// it must be kept the same to be emulated
+const redisIncrByScript = `if redis.call("EXISTS", KEYS[1]) == 1 then return redis.call("INCRBY", KEYS[1], ARGV[1]) else return false end`
+
+// redisIncrBy emulates redisIncrByScript
+func redisIncrBy(db *RedisDB, keys, args []string) interface{} {
+	item := db.s.get(keys[0])
+	if item == nil {
+		return nil
+	}
+	delta, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return errors.New("ERR value is not an integer or out of range")
+	}
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return errors.New("ERR value is not an integer or out of range")
+	}
+	n += delta
+	item.value = strconv.FormatInt(n, 10)
+	return n
+}
+
+type redisHandler func(s *RedisServer, w *respWriter, args []string)
+
+var redisCommands map[string]redisHandler
+
+func init() {
+	redisCommands = map[string]redisHandler{
+		"PING":     (*RedisServer).ping,
+		"SELECT":   (*RedisServer).ok,
+		"GET":      (*RedisServer).cmdGet,
+		"SET":      (*RedisServer).cmdSet,
+		"SETNX":    (*RedisServer).cmdSetNX,
+		"MGET":     (*RedisServer).cmdMGet,
+		"DEL":      (*RedisServer).cmdDel,
+		"UNLINK":   (*RedisServer).cmdDel,
+		"EXISTS":   (*RedisServer).cmdExists,
+		"EXPIRE":   (*RedisServer).cmdExpire,
+		"PEXPIRE":  (*RedisServer).cmdExpire,
+		"PERSIST":  (*RedisServer).cmdPersist,
+		"TTL":      (*RedisServer).cmdTTL,
+		"PTTL":     (*RedisServer).cmdTTL,
+		"INCR":     (*RedisServer).cmdIncr,
+		"DECR":     (*RedisServer).cmdIncr,
+		"INCRBY":   (*RedisServer).cmdIncr,
+		"DECRBY":   (*RedisServer).cmdIncr,
+		"KEYS":     (*RedisServer).cmdKeys,
+		"SCAN":     (*RedisServer).cmdScan,
+		"FLUSHDB":  (*RedisServer).cmdFlush,
+		"FLUSHALL": (*RedisServer).cmdFlush,
+		"EVAL":     (*RedisServer).cmdEval,
+	}
+}
+
+// arity is the minimal count of args includes the command name
+var redisArity = map[string]int{
+	"GET": 2, "SET": 3, "SETNX": 3, "MGET": 2, "DEL": 2, "UNLINK": 2, "EXISTS": 2,
+	"EXPIRE": 3, "PEXPIRE": 3, "PERSIST": 2, "TTL": 2, "PTTL": 2,
+	"INCR": 2, "DECR": 2, "INCRBY": 3, "DECRBY": 3, "KEYS": 2, "SCAN": 2,
+	"WATCH": 2, "EVAL": 3,
+}
+
+// redisWrites are commands write the first key, or all keys if multiple
+var redisWrites = map[string]bool{
+	"SET": true, "SETNX": true, "DEL": true, "UNLINK": true, "EXPIRE": true, "PEXPIRE": true,
+	"PERSIST": true, "INCR": true, "DECR": true, "INCRBY": true, "DECRBY": true,
+	"EVAL": true,
+}
+
+// RedisScript emulates lua script of EVAL, returns nil, int, int64, string or error reply
+type RedisScript func(db *RedisDB, keys, args []string) interface{}
+
+// RedisDB accesses keys in RedisScript
+type RedisDB struct {
+	s *RedisServer
+}
+
+// Get returns value of alive key
+func (db *RedisDB) Get(key string) (string, bool) {
+	if item := db.s.get(key); item != nil {
+		return item.value, true
+	}
+	return "", false
+}
+
+// Set sets value of key expires in ttl, never expires if ttl is 0
+func (db *RedisDB) Set(key, value string, ttl time.Duration) {
+	item := db.s.create(key, value)
+	if ttl > 0 {
+		item.expiredAt = time.Now().Add(ttl)
+	}
+}
+
+// Del deletes key, reports whether the key exists
+func (db *RedisDB) Del(key string) bool {
+	if db.s.get(key) == nil {
+		return false
+	}
+	delete(db.s.items, key)
+	return true
+}
+
+// Expire sets timeout of key, reports whether the key exists
+func (db *RedisDB) Expire(key string, ttl time.Duration) bool {
+	item := db.s.get(key)
+	if item == nil {
+		return false
+	}
+	item.expiredAt = time.Now().Add(ttl)
+	return true
+}
+
+// RegisterScript emulates the lua script src of EVAL by Go func
+func (s *RedisServer) RegisterScript(src string, script RedisScript) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[src] = script
+}
+
+// redisConn is the transaction and subscription state of a connection
+type redisConn struct {
+	watched map[string]uint64
+	epoch   uint64
+	multi   bool
+	queued  [][]string
+
+	conn     net.Conn
+	wmu      sync.Mutex // guards writes of replies and published messages
+	channels map[string]bool
+}
+
+// write sends bytes of replies to the client
+func (c *redisConn) write(b []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(b)
+	return err
+}
+
+func (c *redisConn) reset() {
+	c.watched = nil
+	c.multi = false
+	c.queued = nil
+}
+
+func (s *RedisServer) serve(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	var buf bytes.Buffer
+	w := &respWriter{bufio.NewWriter(&buf)}
+	c := &redisConn{conn: conn, channels: make(map[string]bool)}
+	defer s.unsubscribe(c, nil)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		s.exec(c, w, args)
+		w.Flush()
+		if err := c.write(buf.Bytes()); err != nil {
+			return
+		}
+		buf.Reset()
+	}
+}
+
+func (s *RedisServer) exec(c *redisConn, w *respWriter, args []string) {
+	name := strings.ToUpper(args[0])
+	args[0] = name
+
+	switch name {
+	case "WATCH", "MULTI", "EXEC", "DISCARD", "UNWATCH":
+		s.transaction(c, w, args)
+		return
+	case "SUBSCRIBE", "UNSUBSCRIBE", "PUBLISH":
+		s.pubsub(c, w, args)
+		return
+	}
+
+	_, ok := redisCommands[name]
+	if !ok {
+		w.error(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+		return
+	}
+	if len(args) < redisArity[name] {
+		w.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+		return
+	}
+	if c.multi {
+		c.queued = append(c.queued, args)
+		w.status("QUEUED")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.call(w, args)
+}
+
+// call runs command with the lock held
+func (s *RedisServer) call(w *respWriter, args []string) {
+	redisCommands[args[0]](s, w, args)
+	if !redisWrites[args[0]] {
+		return
+	}
+	keys := args[1:2]
+	switch args[0] {
+	case "DEL", "UNLINK":
+		keys = args[1:]
+	case "EVAL":
+		keys = evalKeys(args)
+	}
+	for _, key := range keys {
+		s.versions[key]++
+	}
+}
+
+func (s *RedisServer) transaction(c *redisConn, w *respWriter, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch args[0] {
+	case "WATCH":
+		if c.multi {
+			w.error("ERR WATCH inside MULTI is not allowed")
+			return
+		}
+		if len(args) < redisArity["WATCH"] {
+			w.error("ERR wrong number of arguments for 'watch' command")
+			return
+		}
+		if c.watched == nil {
+			c.watched = make(map[string]uint64)
+			c.epoch = s.epoch
+		}
+		for _, key := range args[1:] {
+			if _, ok := c.watched[key]; !ok {
+				c.watched[key] = s.versions[key]
+			}
+		}
+		w.status("OK")
+
+	case "UNWATCH":
+		c.watched = nil
+		w.status("OK")
+
+	case "MULTI":
+		if c.multi {
+			w.error("ERR MULTI calls can not be nested")
+			return
+		}
+		c.multi = true
+		w.status("OK")
+
+	case "DISCARD":
+		if !c.multi {
+			w.error("ERR DISCARD without MULTI")
+			return
+		}
+		c.reset()
+		w.status("OK")
+
+	case "EXEC":
+		if !c.multi {
+			w.error("ERR EXEC without MULTI")
+			return
+		}
+		defer c.reset()
+
+		// aborted if any watched key changed
+		dirty := c.watched != nil && c.epoch != s.epoch
+		for key, version := range c.watched {
+			dirty = dirty || s.versions[key] != version
+		}
+		if dirty {
+			w.WriteString("*-1\r\n")
+			return
+		}
+
+		w.array(len(c.queued))
+		for _, args := range c.queued {
+			s.call(w, args)
+		}
+	}
+}
+
+func (s *RedisServer) pubsub(c *redisConn, w *respWriter, args []string) {
+	switch args[0] {
+	case "SUBSCRIBE":
+		if len(args) < 2 {
+			w.error("ERR wrong number of arguments for 'subscribe' command")
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, channel := range args[1:] {
+			if !c.channels[channel] {
+				c.channels[channel] = true
+				if s.subscribers[channel] == nil {
+					s.subscribers[channel] = make(map[*redisConn]bool)
+				}
+				s.subscribers[channel][c] = true
+			}
+			w.array(3)
+			w.bulk("subscribe")
+			w.bulk(channel)
+			w.integer(int64(len(c.channels)))
+		}
+
+	case "UNSUBSCRIBE":
+		channels := s.unsubscribe(c, args[1:])
+		if len(channels) == 0 {
+			w.array(3)
+			w.bulk("unsubscribe")
+			w.nil()
+			w.integer(0)
+		}
+		for i, channel := range channels {
+			w.array(3)
+			w.bulk("unsubscribe")
+			w.bulk(channel)
+			w.integer(int64(len(c.channels) + len(channels) - i - 1))
+		}
+
+	case "PUBLISH":
+		if len(args) != 3 {
+			w.error("ERR wrong number of arguments for 'publish' command")
+			return
+		}
+		s.mu.Lock()
+		var subs []*redisConn
+		for sub := range s.subscribers[args[1]] {
+			subs = append(subs, sub)
+		}
+		s.mu.Unlock()
+
+		// writes to subscribers without the server locked
+		var buf bytes.Buffer
+		mw := &respWriter{bufio.NewWriter(&buf)}
+		mw.array(3)
+		mw.bulk("message")
+		mw.bulk(args[1])
+		mw.bulk(args[2])
+		mw.Flush()
+		for _, sub := range subs {
+			sub.write(buf.Bytes())
+		}
+		w.integer(int64(len(subs)))
+	}
+}
+
+// unsubscribe removes channels of connection, all if channels is empty,
+// returns the removed channels
+func (s *RedisServer) unsubscribe(c *redisConn, channels []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(channels) == 0 {
+		for channel := range c.channels {
+			channels = append(channels, channel)
+		}
+		sort.Strings(channels)
+	}
+	for _, channel := range channels {
+		delete(c.channels, channel)
+		delete(s.subscribers[channel], c)
+	}
+	return channels
+}
+
+func (s *RedisServer) ping(w *respWriter, args []string) {
+	w.status("PONG")
+}
+
+func (s *RedisServer) ok(w *respWriter, args []string) {
+	w.status("OK")
+}
+
+func (s *RedisServer) cmdGet(w *respWriter, args []string) {
+	if item := s.get(args[1]); item != nil {
+		w.bulk(item.value)
+		return
+	}
+	w.nil()
+}
+
+func (s *RedisServer) cmdSet(w *respWriter, args []string) {
+	var (
+		expiredAt time.Time
+		nx, xx    bool
+	)
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				w.error("ERR syntax error")
+				return
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || n <= 0 {
+				w.error("ERR invalid expire time in 'set' command")
+				return
+			}
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			expiredAt = time.Now().Add(time.Duration(n) * unit)
+		default:
+			w.error("ERR syntax error")
+			return
+		}
+	}
+
+	exists := s.get(args[1]) != nil
+	if nx && exists || xx && !exists {
+		w.nil()
+		return
+	}
+	s.create(args[1], args[2]).expiredAt = expiredAt
+	w.status("OK")
+}
+
+func (s *RedisServer) cmdSetNX(w *respWriter, args []string) {
+	if s.get(args[1]) != nil {
+		w.integer(0)
+		return
+	}
+	s.create(args[1], args[2])
+	w.integer(1)
+}
+
+func (s *RedisServer) cmdMGet(w *respWriter, args []string) {
+	w.array(len(args) - 1)
+	for _, key := range args[1:] {
+		if item := s.get(key); item != nil {
+			w.bulk(item.value)
+		} else {
+			w.nil()
+		}
+	}
+}
+
+func (s *RedisServer) cmdDel(w *respWriter, args []string) {
+	var n int64
+	for _, key := range args[1:] {
+		if s.get(key) != nil {
+			delete(s.items, key)
+			n++
+		}
+	}
+	w.integer(n)
+}
+
+func (s *RedisServer) cmdExists(w *respWriter, args []string) {
+	var n int64
+	for _, key := range args[1:] {
+		if s.get(key) != nil {
+			n++
+		}
+	}
+	w.integer(n)
+}
+
+func (s *RedisServer) cmdExpire(w *respWriter, args []string) {
+	n, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		w.error("ERR value is not an integer or out of range")
+		return
+	}
+	item := s.get(args[1])
+	if item == nil {
+		w.integer(0)
+		return
+	}
+
+	unit := time.Second
+	if args[0] == "PEXPIRE" {
+		unit = time.Millisecond
+	}
+	if n <= 0 {
+		// non-positive timeout deletes the key
+		delete(s.items, args[1])
+	} else {
+		item.expiredAt = time.Now().Add(time.Duration(n) * unit)
+	}
+	w.integer(1)
+}
+
+func (s *RedisServer) cmdPersist(w *respWriter, args []string) {
+	item := s.get(args[1])
+	if item == nil || item.expiredAt.IsZero() {
+		w.integer(0)
+		return
+	}
+	item.expiredAt = time.Time{}
+	w.integer(1)
+}
+
+func (s *RedisServer) cmdTTL(w *respWriter, args []string) {
+	item := s.get(args[1])
+	switch {
+	case item == nil:
+		w.integer(-2)
+	case item.expiredAt.IsZero():
+		w.integer(-1)
+	case args[0] == "PTTL":
+		w.integer(int64(time.Until(item.expiredAt) / time.Millisecond))
+	default:
+		w.integer(int64((time.Until(item.expiredAt) + time.Second - 1) / time.Second))
+	}
+}
+
+func (s *RedisServer) cmdIncr(w *respWriter, args []string) {
+	delta := int64(1)
+	if len(args) > 2 {
+		var err error
+		if delta, err = strconv.ParseInt(args[2], 10, 64); err != nil {
+			w.error("ERR value is not an integer or out of range")
+			return
+		}
+	}
+	if args[0] == "DECR" || args[0] == "DECRBY" {
+		delta = -delta
+	}
+
+	// missing key is created as 0
+	item := s.get(args[1])
+	if item == nil {
+		item = s.create(args[1], "0")
+	}
+	n, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		w.error("ERR value is not an integer or out of range")
+		return
+	}
+	n += delta
+	item.value = strconv.FormatInt(n, 10)
+	w.integer(n)
+}
+
+func (s *RedisServer) cmdKeys(w *respWriter, args []string) {
+	keys := s.match(args[1])
+	w.array(len(keys))
+	for _, key := range keys {
+		w.bulk(key)
+	}
+}
+
+// cmdScan iterates keys in order of creation, the cursor is the seq of next key,
+// so keys deleted or created while scanning don't shift the others
+func (s *RedisServer) cmdScan(w *respWriter, args []string) {
+	cursor, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		w.error("ERR invalid cursor")
+		return
+	}
+	pattern, count := "*", 10
+	for i := 2; i+1 < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(args[i+1]); err != nil || count <= 0 {
+				w.error("ERR syntax error")
+				return
+			}
+		default:
+			w.error("ERR syntax error")
+			return
+		}
+	}
+
+	var items []*redisItem
+	keys := make(map[*redisItem]string)
+	for key := range s.items {
+		if item := s.get(key); item != nil && item.seq >= cursor {
+			items = append(items, item)
+			keys[item] = key
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].seq < items[j].seq
+	})
+
+	var next uint64
+	if len(items) > count {
+		next = items[count].seq
+		items = items[:count]
+	}
+
+	var matched []string
+	for _, item := range items {
+		if key := keys[item]; globMatch(pattern, key) {
+			matched = append(matched, key)
+		}
+	}
+
+	w.array(2)
+	w.bulk(strconv.FormatUint(next, 10))
+	w.array(len(matched))
+	for _, key := range matched {
+		w.bulk(key)
+	}
+}
+
+func (s *RedisServer) cmdEval(w *respWriter, args []string) {
+	script, ok := s.scripts[args[1]]
+	if !ok {
+		w.error("NOSCRIPT No matching script")
+		return
+	}
+	n, err := strconv.Atoi(args[2])
+	if err != nil || n < 0 || 3+n > len(args) {
+		w.error("ERR Number of keys can't be greater than number of args")
+		return
+	}
+
+	switch reply := script(&RedisDB{s}, args[3:3+n], args[3+n:]).(type) {
+	case nil:
+		w.nil()
+	case int:
+		w.integer(int64(reply))
+	case int64:
+		w.integer(reply)
+	case string:
+		w.bulk(reply)
+	case error:
+		w.error(reply.Error())
+	default:
+		w.error(fmt.Sprintf("ERR unsupported reply %T", reply))
+	}
+}
+
+// evalKeys returns keys of EVAL args
+func evalKeys(args []string) []string {
+	n, err := strconv.Atoi(args[2])
+	if err != nil || n < 0 || 3+n > len(args) {
+		return nil
+	}
+	return args[3 : 3+n]
+}
+
+func (s *RedisServer) cmdFlush(w *respWriter, args []string) {
+	s.items = make(map[string]*redisItem)
+	s.versions = make(map[string]uint64)
+	s.epoch++
+	w.status("OK")
+}
+
+// create sets new item of key, replaces the old one
+func (s *RedisServer) create(key, value string) *redisItem {
+	s.seq++
+	item := &redisItem{value: value, seq: s.seq}
+	s.items[key] = item
+	return item
+}
+
+// get returns alive item, expired one is removed
+func (s *RedisServer) get(key string) *redisItem {
+	item, ok := s.items[key]
+	if !ok {
+		return nil
+	}
+	if !item.expiredAt.IsZero() && !time.Now().Before(item.expiredAt) {
+		delete(s.items, key)
+		return nil
+	}
+	return item
+}
+
+// match returns sorted alive keys matched glob pattern
+func (s *RedisServer) match(pattern string) []string {
+	var keys []string
+	for key := range s.items {
+		if s.get(key) != nil && globMatch(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// globMatch supports * ? and \ escape of redis glob-style patterns
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if globMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return len(s) == 0
+}
+
+// readCommand reads array of bulk strings, or inline command
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, errProtocol
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, errProtocol
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+type respWriter struct {
+	*bufio.Writer
+}
+
+func (w *respWriter) status(s string) {
+	w.WriteString("+" + s + "\r\n")
+}
+
+func (w *respWriter) error(s string) {
+	w.WriteString("-" + s + "\r\n")
+}
+
+func (w *respWriter) integer(n int64) {
+	w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (w *respWriter) bulk(s string) {
+	w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (w *respWriter) nil() {
+	w.WriteString("$-1\r\n")
+}
+
+func (w *respWriter) array(n int) {
+	w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
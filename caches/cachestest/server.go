@@ -0,0 +1,77 @@
+package cachestest
+
+import (
+	"net"
+	"sync"
+)
+
+// server accepts connections on random local port
+type server struct {
+	listener net.Listener
+	handle   func(conn net.Conn)
+
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newServer(handle func(conn net.Conn)) (*server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &server{listener: l, handle: handle, conns: make(map[net.Conn]bool)}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns host:port the server listens on
+func (s *server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and closes all connections
+func (s *server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
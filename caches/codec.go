@@ -0,0 +1,144 @@
+package caches
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+var ErrCodecData = errors.New("invalid codec data")
+
+// Codec encodes structured values to bytes stored in CacheProvider
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// bson document must be struct or map, values are wrapped as {v: value}
+type bsonCodec struct{}
+
+type bsonWrapper struct {
+	V interface{} `bson:"v"`
+}
+
+func (bsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return bson.Marshal(bsonWrapper{V: v})
+}
+
+func (bsonCodec) Unmarshal(data []byte, v interface{}) error {
+	var doc struct {
+		V bson.Raw `bson:"v"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return doc.V.Unmarshal(v)
+}
+
+var (
+	// JSONCodec encodes values by encoding/json
+	JSONCodec Codec = jsonCodec{}
+	// GobCodec encodes values by encoding/gob, types of interface values need gob.Register
+	GobCodec Codec = gobCodec{}
+	// BsonCodec encodes values by mgo bson
+	BsonCodec Codec = bsonCodec{}
+)
+
+const (
+	codecRaw  byte = '0'
+	codecGzip byte = '1'
+)
+
+// CompressCodec gzips data of Codec larger than Threshold bytes,
+// the first byte of data marks whether it's compressed
+type CompressCodec struct {
+	Codec     Codec // JSONCodec if nil
+	Threshold int   // compress data larger than bytes, always if 0
+	Level     int   // gzip level, gzip.DefaultCompression if 0
+}
+
+func (c *CompressCodec) codec() Codec {
+	if c.Codec == nil {
+		return JSONCodec
+	}
+	return c.Codec
+}
+
+func (c *CompressCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.codec().Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= c.Threshold {
+		return append([]byte{codecRaw}, data...), nil
+	}
+
+	level := c.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(codecGzip)
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *CompressCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return ErrCodecData
+	}
+
+	switch data[0] {
+	case codecRaw:
+		return c.codec().Unmarshal(data[1:], v)
+	case codecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		raw, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return c.codec().Unmarshal(raw, v)
+	}
+	return ErrCodecData
+}
@@ -0,0 +1,503 @@
+package caches
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wujiu2020/strip"
+	"github.com/wujiu2020/strip/utils"
+)
+
+const DefaultSlowThreshold = 100 * time.Millisecond
+
+// DefaultLatencyBuckets are upper bounds of latency histograms
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+var ErrInstrumentProvider = errors.New("instrument needs provider")
+
+// Results of operations
+const (
+	ResultOK    = "ok"
+	ResultHit   = "hit"
+	ResultMiss  = "miss"
+	ResultError = "error"
+)
+
+// Observation is an operation of instrumented provider
+type Observation struct {
+	Provider  string
+	Prefix    string // of the key, or the shared one of keys of batch operation, empty if mixed
+	Operation string // get, set, touch, delete, incr, decr, has, clean, gc, or get_multi, get_multi_ttl, set_multi, delete_multi, setnx, incrby, decrby, cas, ttl, keys, scan
+	Result    string
+	Latency   time.Duration
+	Hits      int // hit keys of batch lookup
+	Misses    int // missed keys of batch lookup
+}
+
+// MetricsSink receives observations, eg: exports them to a metrics system
+type MetricsSink interface {
+	Observe(obs Observation)
+}
+
+// SinkFunc adapts a function to MetricsSink
+type SinkFunc func(obs Observation)
+
+func (f SinkFunc) Observe(obs Observation) {
+	f(obs)
+}
+
+type InstrumentConfig struct {
+	Provider      CacheProvider
+	Name          string                  // name of the provider in observations
+	Prefix        func(key string) string // key prefix of observations, KeyPrefixBefore(":") if nil
+	Sink          MetricsSink             // optional
+	Logger        strip.Logger            // logs slow operations if not nil
+	SlowThreshold time.Duration           // DefaultSlowThreshold if 0, disabled if negative
+	Buckets       []time.Duration         // DefaultLatencyBuckets if empty
+}
+
+// KeyPrefixBefore returns prefix of key before the first sep, empty if key has no sep
+func KeyPrefixBefore(sep string) func(key string) string {
+	return func(key string) string {
+		if i := strings.Index(key, sep); i >= 0 {
+			return key[:i]
+		}
+		return ""
+	}
+}
+
+// OpStats is stats of an operation, Histogram[i] counts latencies not above Buckets[i],
+// the last one counts the rest
+type OpStats struct {
+	Count     int64
+	Errors    int64
+	Latency   time.Duration // total latency
+	Histogram []int64
+}
+
+// PrefixStats is stats of keys with the same prefix
+type PrefixStats struct {
+	Hits   int64 // hits of get, has and keys of batch lookups
+	Misses int64 // misses of get, has and keys of batch lookups
+	Errors int64
+	Ops    map[string]OpStats
+}
+
+// HitRatio returns hits / (hits + misses), 0 if no lookup
+func (s PrefixStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Stats is snapshot of an instrumented provider
+type Stats struct {
+	Provider string
+	Buckets  []time.Duration
+	Prefixes map[string]PrefixStats
+}
+
+// Instrumented records hits, misses, errors and latencies of Provider.
+// Optional capabilities are forwarded to Provider, ErrUnsupported is returned if Provider doesn't have them,
+// except batch operations which fall back to Batch. Batch operation is observed once,
+// with hits and misses of its keys counted by their prefixes.
+// Slow operations are logged with key prefix and hash, keys may contain sensitive data.
+type Instrumented struct {
+	InstrumentConfig
+
+	mu       sync.Mutex
+	prefixes map[string]*PrefixStats
+}
+
+var (
+	_ CacheProvider    = new(Instrumented)
+	_ BatchProvider    = new(Instrumented)
+	_ NXProvider       = new(Instrumented)
+	_ CounterProvider  = new(Instrumented)
+	_ CASProvider      = new(Instrumented)
+	_ ScanProvider     = new(Instrumented)
+	_ TTLProvider      = new(Instrumented)
+	_ BatchTTLProvider = new(Instrumented)
+)
+
+func NewInstrumented(config InstrumentConfig) (*Instrumented, error) {
+	if config.Provider == nil {
+		return nil, ErrInstrumentProvider
+	}
+	if config.Prefix == nil {
+		config.Prefix = KeyPrefixBefore(":")
+	}
+	if config.SlowThreshold == 0 {
+		config.SlowThreshold = DefaultSlowThreshold
+	}
+	if len(config.Buckets) == 0 {
+		config.Buckets = DefaultLatencyBuckets
+	}
+
+	instrumented := new(Instrumented)
+	instrumented.InstrumentConfig = config
+	instrumented.prefixes = make(map[string]*PrefixStats)
+	return instrumented, nil
+}
+
+func (p *Instrumented) Get(key string) (value utils.StrTo, err error) {
+	start := time.Now()
+	value, err = p.Provider.Get(key)
+	p.observe("get", key, start, lookupResult(err == nil, err))
+	return
+}
+
+func (p *Instrumented) Set(key string, value interface{}, params ...int) (err error) {
+	start := time.Now()
+	err = p.Provider.Set(key, value, params...)
+	p.observe("set", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) Touch(key string, params ...int) (err error) {
+	start := time.Now()
+	err = p.Provider.Touch(key, params...)
+	p.observe("touch", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) Delete(key string) (err error) {
+	start := time.Now()
+	err = p.Provider.Delete(key)
+	p.observe("delete", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) Incr(key string, params ...int) (err error) {
+	start := time.Now()
+	err = p.Provider.Incr(key, params...)
+	p.observe("incr", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) Decr(key string, params ...int) (err error) {
+	start := time.Now()
+	err = p.Provider.Decr(key, params...)
+	p.observe("decr", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) Has(key string) (has bool, err error) {
+	start := time.Now()
+	has, err = p.Provider.Has(key)
+	p.observe("has", key, start, lookupResult(has, err))
+	return
+}
+
+func (p *Instrumented) Clean() (err error) {
+	start := time.Now()
+	err = p.Provider.Clean()
+	p.observe("clean", "", start, result(err))
+	return
+}
+
+func (p *Instrumented) GC() (err error) {
+	start := time.Now()
+	err = p.Provider.GC()
+	p.observe("gc", "", start, result(err))
+	return
+}
+
+func (p *Instrumented) GetMulti(keys []string) (values map[string]utils.StrTo, err error) {
+	start := time.Now()
+	values, err = Batch(p.Provider).GetMulti(keys)
+	p.observeKeys("get_multi", keys, start, result(err), func(key string) bool {
+		_, hit := values[key]
+		return hit
+	})
+	return
+}
+
+func (p *Instrumented) GetMultiTTL(keys []string) (values map[string]TTLValue, err error) {
+	batch, ok := p.Provider.(BatchTTLProvider)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	start := time.Now()
+	values, err = batch.GetMultiTTL(keys)
+	p.observeKeys("get_multi_ttl", keys, start, result(err), func(key string) bool {
+		_, hit := values[key]
+		return hit
+	})
+	return
+}
+
+func (p *Instrumented) SetMulti(values map[string]interface{}, params ...int) (err error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	start := time.Now()
+	err = Batch(p.Provider).SetMulti(values, params...)
+	p.observeKeys("set_multi", keys, start, result(err), nil)
+	return
+}
+
+func (p *Instrumented) DeleteMulti(keys []string) (err error) {
+	start := time.Now()
+	err = Batch(p.Provider).DeleteMulti(keys)
+	p.observeKeys("delete_multi", keys, start, result(err), nil)
+	return
+}
+
+func (p *Instrumented) SetNX(key string, value interface{}, params ...int) (ok bool, err error) {
+	nx, supported := p.Provider.(NXProvider)
+	if !supported {
+		return false, ErrUnsupported
+	}
+	start := time.Now()
+	ok, err = nx.SetNX(key, value, params...)
+	p.observe("setnx", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) IncrBy(key string, delta int64) (n int64, err error) {
+	counter, ok := p.Provider.(CounterProvider)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	start := time.Now()
+	n, err = counter.IncrBy(key, delta)
+	p.observe("incrby", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) DecrBy(key string, delta int64) (n int64, err error) {
+	counter, ok := p.Provider.(CounterProvider)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	start := time.Now()
+	n, err = counter.DecrBy(key, delta)
+	p.observe("decrby", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) CAS(key string, old, new interface{}, params ...int) (ok bool, err error) {
+	cas, supported := p.Provider.(CASProvider)
+	if !supported {
+		return false, ErrUnsupported
+	}
+	start := time.Now()
+	ok, err = cas.CAS(key, old, new, params...)
+	p.observe("cas", key, start, result(err))
+	return
+}
+
+func (p *Instrumented) TTL(key string) (ttl time.Duration, err error) {
+	provider, ok := p.Provider.(TTLProvider)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	start := time.Now()
+	ttl, err = provider.TTL(key)
+	p.observe("ttl", key, start, lookupResult(err == nil, err))
+	return
+}
+
+// Keys is observed with prefix of the scanned prefix
+func (p *Instrumented) Keys(prefix string) (keys []string, err error) {
+	scan, ok := p.Provider.(ScanProvider)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	start := time.Now()
+	keys, err = scan.Keys(prefix)
+	p.observe("keys", prefix, start, result(err))
+	return
+}
+
+// Scan is observed with prefix of the scanned prefix, latency includes calls of fn
+func (p *Instrumented) Scan(prefix string, fn func(key string) error) (err error) {
+	scan, ok := p.Provider.(ScanProvider)
+	if !ok {
+		return ErrUnsupported
+	}
+	start := time.Now()
+	err = scan.Scan(prefix, fn)
+	p.observe("scan", prefix, start, result(err))
+	return
+}
+
+// Stats returns snapshot of recorded stats
+func (p *Instrumented) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := Stats{
+		Provider: p.Name,
+		Buckets:  append([]time.Duration(nil), p.Buckets...),
+		Prefixes: make(map[string]PrefixStats, len(p.prefixes)),
+	}
+	for prefix, s := range p.prefixes {
+		ops := make(map[string]OpStats, len(s.Ops))
+		for name, op := range s.Ops {
+			op.Histogram = append([]int64(nil), op.Histogram...)
+			ops[name] = op
+		}
+		ps := *s
+		ps.Ops = ops
+		stats.Prefixes[prefix] = ps
+	}
+	return stats
+}
+
+// Reset clears recorded stats
+func (p *Instrumented) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prefixes = make(map[string]*PrefixStats)
+}
+
+func (p *Instrumented) observe(op, key string, start time.Time, res string) {
+	var keys []string
+	if key != "" {
+		keys = []string{key}
+	}
+	p.observeKeys(op, keys, start, res, nil)
+}
+
+// observeKeys records one observation of the operation, hit reports whether each key
+// is hit for batch lookup, the keys are not counted if the operation failed
+func (p *Instrumented) observeKeys(op string, keys []string, start time.Time, res string, hit func(key string) bool) {
+	obs := Observation{
+		Provider:  p.Name,
+		Operation: op,
+		Result:    res,
+		Latency:   time.Since(start),
+	}
+
+	var lookups map[string]*PrefixStats
+	if hit != nil && res != ResultError {
+		lookups = make(map[string]*PrefixStats)
+	}
+	for i, key := range keys {
+		prefix := p.Prefix(key)
+		if i == 0 {
+			obs.Prefix = prefix
+		} else if prefix != obs.Prefix {
+			obs.Prefix = ""
+		}
+		if lookups == nil {
+			continue
+		}
+		s := lookups[prefix]
+		if s == nil {
+			s = new(PrefixStats)
+			lookups[prefix] = s
+		}
+		if hit(key) {
+			s.Hits++
+			obs.Hits++
+		} else {
+			s.Misses++
+			obs.Misses++
+		}
+	}
+	p.record(obs, lookups)
+
+	if p.Sink != nil {
+		p.Sink.Observe(obs)
+	}
+	if p.Logger != nil && p.SlowThreshold > 0 && obs.Latency >= p.SlowThreshold {
+		p.Logger.Warnf("caches: slow %s %s%s %s, %s", p.Name, op, p.describe(keys), res, obs.Latency)
+	}
+}
+
+// describe keys by prefix and hash, eg: ` "user" #1a2b3c4d`, or count of keys
+func (p *Instrumented) describe(keys []string) string {
+	switch len(keys) {
+	case 0:
+		return ""
+	case 1:
+		h := fnv.New32a()
+		h.Write([]byte(keys[0]))
+		return fmt.Sprintf(" %q #%08x", p.Prefix(keys[0]), h.Sum32())
+	}
+	return fmt.Sprintf(" %d keys", len(keys))
+}
+
+// record adds obs to stats of its prefix, and hits and misses of batch lookup to stats of their prefixes
+func (p *Instrumented) record(obs Observation, lookups map[string]*PrefixStats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for prefix, lookup := range lookups {
+		s := p.prefixStats(prefix)
+		s.Hits += lookup.Hits
+		s.Misses += lookup.Misses
+	}
+
+	s := p.prefixStats(obs.Prefix)
+	switch obs.Result {
+	case ResultHit:
+		s.Hits++
+	case ResultMiss:
+		s.Misses++
+	case ResultError:
+		s.Errors++
+	}
+
+	op := s.Ops[obs.Operation]
+	if op.Histogram == nil {
+		op.Histogram = make([]int64, len(p.Buckets)+1)
+	}
+	op.Count++
+	if obs.Result == ResultError {
+		op.Errors++
+	}
+	op.Latency += obs.Latency
+	i := 0
+	for i < len(p.Buckets) && obs.Latency > p.Buckets[i] {
+		i++
+	}
+	op.Histogram[i]++
+	s.Ops[obs.Operation] = op
+}
+
+func (p *Instrumented) prefixStats(prefix string) *PrefixStats {
+	s := p.prefixes[prefix]
+	if s == nil {
+		s = &PrefixStats{Ops: make(map[string]OpStats)}
+		p.prefixes[prefix] = s
+	}
+	return s
+}
+
+// result treats ErrMissedKey as ok, eg: delete missed key
+func result(err error) string {
+	if err != nil && err != ErrMissedKey {
+		return ResultError
+	}
+	return ResultOK
+}
+
+func lookupResult(hit bool, err error) string {
+	switch {
+	case err == ErrMissedKey:
+		return ResultMiss
+	case err != nil:
+		return ResultError
+	case hit:
+		return ResultHit
+	}
+	return ResultMiss
+}
@@ -0,0 +1,145 @@
+package caches
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip"
+)
+
+type slowProvider struct {
+	CacheProvider
+}
+
+func (p slowProvider) Set(key string, value interface{}, params ...int) error {
+	time.Sleep(5 * time.Millisecond)
+	return p.CacheProvider.Set(key, value, params...)
+}
+
+func (p slowProvider) Incr(key string, params ...int) error {
+	return errors.New("broken")
+}
+
+func Test_Instrumented(t *testing.T) {
+	var buf bytes.Buffer
+	logger := strip.NewLogger(log.New(&buf, "", 0))
+
+	var observations []Observation
+	memory, _ := NewMemoryProvider(MemoryConfig{})
+	p, err := NewInstrumented(InstrumentConfig{
+		Provider:      slowProvider{memory},
+		Name:          "memory",
+		Sink:          SinkFunc(func(obs Observation) { observations = append(observations, obs) }),
+		Logger:        logger,
+		SlowThreshold: 5 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, p.Set("user:1", "a"))
+	p.Get("user:1")
+	p.Get("user:2")
+	p.Has("user:1")
+	p.Get("plain")
+	assert.Error(t, p.Incr("user:1"))
+
+	stats := p.Stats()
+	assert.Equal(t, "memory", stats.Provider)
+	assert.Equal(t, DefaultLatencyBuckets, stats.Buckets)
+
+	user := stats.Prefixes["user"]
+	assert.Equal(t, int64(2), user.Hits)
+	assert.Equal(t, int64(1), user.Misses)
+	assert.Equal(t, int64(1), user.Errors)
+	assert.InDelta(t, 2.0/3, user.HitRatio(), 0.001)
+	assert.Equal(t, int64(2), user.Ops["get"].Count)
+	assert.Equal(t, int64(1), user.Ops["incr"].Errors)
+
+	set := user.Ops["set"]
+	assert.True(t, set.Latency >= 5*time.Millisecond)
+	assert.Len(t, set.Histogram, len(DefaultLatencyBuckets)+1)
+	assert.Equal(t, []int64{0, 0}, set.Histogram[:2], "above 5ms")
+
+	assert.Equal(t, int64(1), stats.Prefixes[""].Misses)
+
+	assert.Len(t, observations, 6)
+	assert.Equal(t, Observation{Provider: "memory", Prefix: "user", Operation: "get", Result: ResultMiss, Latency: observations[2].Latency}, observations[2])
+
+	// key is logged by prefix and hash
+	assert.True(t, strings.Contains(buf.String(), `slow memory set "user" #`), buf.String())
+	assert.False(t, strings.Contains(buf.String(), "user:1"), buf.String())
+	assert.Equal(t, 1, strings.Count(buf.String(), "slow"))
+
+	// snapshot is not changed
+	p.Get("user:1")
+	assert.Equal(t, int64(2), user.Ops["get"].Count)
+	p.Reset()
+	assert.Len(t, p.Stats().Prefixes, 0)
+}
+
+func Test_InstrumentedCapabilities(t *testing.T) {
+	memory, _ := NewMemoryProvider(MemoryConfig{})
+	p, _ := NewInstrumented(InstrumentConfig{Provider: memory, Name: "memory"})
+
+	assert.NoError(t, p.SetMulti(map[string]interface{}{"user:1": 1, "user:2": 2}))
+	values, err := p.GetMulti([]string{"user:1", "user:2", "user:3"})
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+
+	ok, err := p.SetNX("user:1", 3)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	n, err := p.IncrBy("user:1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	ok, err = p.CAS("user:1", 3, 4)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	keys, err := p.Keys("user:")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	// batch operation is observed once, its keys are counted
+	user := p.Stats().Prefixes["user"]
+	assert.Equal(t, int64(1), user.Ops["set_multi"].Count)
+	assert.Equal(t, int64(1), user.Ops["get_multi"].Count)
+	assert.Equal(t, int64(1), sum(user.Ops["get_multi"].Histogram))
+	assert.Equal(t, int64(2), user.Hits)
+	assert.Equal(t, int64(1), user.Misses)
+	for _, op := range []string{"setnx", "incrby", "cas", "keys"} {
+		assert.Equal(t, int64(1), user.Ops[op].Count, op)
+	}
+
+	// keys of mixed prefixes
+	var observations []Observation
+	p.Sink = SinkFunc(func(obs Observation) { observations = append(observations, obs) })
+	p.GetMulti([]string{"user:1", "post:1"})
+	if assert.Len(t, observations, 1) {
+		assert.Equal(t, "", observations[0].Prefix)
+		assert.Equal(t, 1, observations[0].Hits)
+		assert.Equal(t, 1, observations[0].Misses)
+	}
+	stats := p.Stats()
+	assert.Equal(t, int64(3), stats.Prefixes["user"].Hits)
+	assert.Equal(t, int64(1), stats.Prefixes["post"].Misses)
+	assert.Equal(t, int64(1), stats.Prefixes[""].Ops["get_multi"].Count)
+
+	// capabilities of provider are not faked
+	q, _ := NewInstrumented(InstrumentConfig{Provider: slowProvider{memory}})
+	_, err = q.IncrBy("user:1", 1)
+	assert.Equal(t, ErrUnsupported, err)
+	values, err = q.GetMulti([]string{"user:1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "4", values["user:1"].String())
+}
+
+func sum(counts []int64) (n int64) {
+	for _, c := range counts {
+		n += c
+	}
+	return
+}
@@ -0,0 +1,281 @@
+package caches
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wujiu2020/strip"
+	"github.com/wujiu2020/strip/utils"
+)
+
+const (
+	DefaultLockTTL  = 10 // seconds
+	DefaultLockWait = 3 * time.Second
+
+	LockSuffix = ":lock"
+)
+
+var (
+	ErrLoaderProvider = errors.New("loader needs provider")
+	ErrLoaderLock     = errors.New("loader lock needs provider supports SetNX")
+	ErrLoadPanic      = errors.New("load panicked")
+
+	errLoadedEntry = errors.New("invalid loaded entry")
+)
+
+type LoaderConfig struct {
+	Provider    CacheProvider
+	Codec       Codec            // codec of loaded values, JSONCodec if nil
+	StaleTTL    int              // seconds serves stale value while refreshing in background, disabled if 0
+	NegativeTTL int              // seconds caches negative load result, disabled if 0
+	IsNegative  func(error) bool // reports whether load error is negative result, default is ErrMissedKey
+	Lock        bool             // take lock of key through SetNX of provider before load
+	LockTTL     int              // seconds of the lock, DefaultLockTTL if 0
+	LockWait    time.Duration    // max duration waits for loading of lock holder, DefaultLockWait if 0
+	Logger      strip.Logger     // logs panics of load in background refresh if not nil
+}
+
+// Loader is read-through cache of CacheProvider, concurrent loads of same key
+// are collapsed into one in-process, or across instances with Lock
+type Loader struct {
+	LoaderConfig
+
+	mu    sync.Mutex
+	calls map[string]*loadCall
+
+	now func() time.Time
+}
+
+type loadCall struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+// loadedEntry is the cached value with expiry of freshness,
+// encoded as flag, unix milliseconds of expiry and data: v1571468400000:{"id":1}
+type loadedEntry struct {
+	negative  bool
+	expiredAt int64 // unix milliseconds, never stale if 0
+	data      []byte
+}
+
+func (e *loadedEntry) stale(now time.Time) bool {
+	return e.expiredAt > 0 && now.UnixNano()/int64(time.Millisecond) >= e.expiredAt
+}
+
+func (e *loadedEntry) result() ([]byte, error) {
+	if e.negative {
+		return nil, ErrMissedKey
+	}
+	return e.data, nil
+}
+
+func (e *loadedEntry) String() string {
+	flag := "v"
+	if e.negative {
+		flag = "n"
+	}
+	return flag + strconv.FormatInt(e.expiredAt, 10) + ":" + string(e.data)
+}
+
+func parseLoadedEntry(s string) (*loadedEntry, error) {
+	i := strings.IndexByte(s, ':')
+	if i < 1 || s[0] != 'v' && s[0] != 'n' {
+		return nil, errLoadedEntry
+	}
+	expiredAt, err := strconv.ParseInt(s[1:i], 10, 64)
+	if err != nil {
+		return nil, errLoadedEntry
+	}
+	return &loadedEntry{
+		negative:  s[0] == 'n',
+		expiredAt: expiredAt,
+		data:      []byte(s[i+1:]),
+	}, nil
+}
+
+func NewLoader(config LoaderConfig) (*Loader, error) {
+	if config.Provider == nil {
+		return nil, ErrLoaderProvider
+	}
+	if _, ok := config.Provider.(NXProvider); config.Lock && !ok {
+		return nil, ErrLoaderLock
+	}
+	if config.Codec == nil {
+		config.Codec = JSONCodec
+	}
+	if config.IsNegative == nil {
+		config.IsNegative = func(err error) bool {
+			return err == ErrMissedKey
+		}
+	}
+	if config.LockTTL == 0 {
+		config.LockTTL = DefaultLockTTL
+	}
+	if config.LockWait == 0 {
+		config.LockWait = DefaultLockWait
+	}
+
+	loader := new(Loader)
+	loader.LoaderConfig = config
+	loader.calls = make(map[string]*loadCall)
+	loader.now = time.Now
+	return loader, nil
+}
+
+// GetOrLoad decodes cached value of key into dst. When missed, calls load and caches
+// the result with ttl seconds, the same as timeout of CacheProvider.Set.
+// Returns ErrMissedKey if negative result is cached.
+func (l *Loader) GetOrLoad(key string, ttl int, dst interface{}, load func() (interface{}, error)) error {
+	var (
+		data []byte
+		err  error
+	)
+	if entry, ok := l.lookup(key); ok {
+		if entry.stale(l.now()) {
+			go l.refresh(key, ttl, load)
+		}
+		data, err = entry.result()
+	} else {
+		data, err = l.do(key, ttl, load)
+	}
+	if err != nil {
+		return err
+	}
+	return l.Codec.Unmarshal(data, dst)
+}
+
+// refresh loads stale key in background, panic of load is recovered and the stale value is kept
+func (l *Loader) refresh(key string, ttl int, load func() (interface{}, error)) {
+	defer func() {
+		if err := recover(); err != nil && l.Logger != nil {
+			l.Logger.Errorf("caches: refresh %q panicked, %v", key, err)
+		}
+	}()
+	l.do(key, ttl, load)
+}
+
+// lookup returns cached entry, errors of provider are treated as missed
+func (l *Loader) lookup(key string) (*loadedEntry, bool) {
+	v, err := l.Provider.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	entry, err := parseLoadedEntry(v.String())
+	if err != nil {
+		return nil, false
+	}
+	return entry, true
+}
+
+// do collapses concurrent loads of key
+func (l *Loader) do(key string, ttl int, load func() (interface{}, error)) ([]byte, error) {
+	l.mu.Lock()
+	if c, ok := l.calls[key]; ok {
+		l.mu.Unlock()
+		c.wg.Wait()
+		return c.data, c.err
+	}
+	c := &loadCall{err: ErrLoadPanic}
+	c.wg.Add(1)
+	l.calls[key] = c
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		delete(l.calls, key)
+		l.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	c.data, c.err = l.load(key, ttl, load)
+	return c.data, c.err
+}
+
+func (l *Loader) load(key string, ttl int, load func() (interface{}, error)) ([]byte, error) {
+	if l.Lock {
+		lockKey := key + LockSuffix
+		token, err := utils.RandomCreateString(16)
+		if err != nil {
+			return nil, err
+		}
+		locked, err := l.Provider.(NXProvider).SetNX(lockKey, token, l.LockTTL)
+		switch {
+		case err != nil:
+			// load without lock if the provider fails
+		case locked:
+			defer l.unlock(lockKey, token)
+			// loaded by other holder just now
+			if entry, ok := l.lookup(key); ok && !entry.stale(l.now()) {
+				return entry.result()
+			}
+		default:
+			if entry, ok := l.wait(key); ok {
+				return entry.result()
+			}
+		}
+	}
+
+	value, err := load()
+	if err != nil {
+		if l.NegativeTTL > 0 && l.IsNegative(err) {
+			l.store(key, &loadedEntry{negative: true}, l.NegativeTTL)
+		}
+		return nil, err
+	}
+
+	data, err := l.Codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	l.store(key, &loadedEntry{data: data}, ttl)
+	return data, nil
+}
+
+// unlock releases the lock if it's still held by token, the lock may be expired
+// and taken by others if load runs longer than LockTTL.
+// With CASProvider the lock is swapped to an expiring tombstone, waiters of it find
+// the stored entry. Otherwise it's checked and deleted, which is not atomic.
+func (l *Loader) unlock(lockKey, token string) {
+	if cas, ok := l.Provider.(CASProvider); ok {
+		cas.CAS(lockKey, token, "", 1)
+		return
+	}
+	if v, err := l.Provider.Get(lockKey); err == nil && v.String() == token {
+		l.Provider.Delete(lockKey)
+	}
+}
+
+// wait polls fresh entry loaded by the lock holder until LockWait passed
+func (l *Loader) wait(key string) (*loadedEntry, bool) {
+	interval := 10 * time.Millisecond
+	deadline := time.Now().Add(l.LockWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		if entry, ok := l.lookup(key); ok && !entry.stale(l.now()) {
+			return entry, true
+		}
+		if interval < 200*time.Millisecond {
+			interval *= 2
+		}
+	}
+	return nil, false
+}
+
+// store caches entry and keeps it StaleTTL longer than its freshness, errors are ignored
+func (l *Loader) store(key string, entry *loadedEntry, ttl int) {
+	timeout := getTimeoutDur(ttl)
+	if timeout == 0 {
+		l.Provider.Set(key, entry.String(), -1)
+		return
+	}
+
+	entry.expiredAt = l.now().Add(timeout).UnixNano() / int64(time.Millisecond)
+	// rounded up, 0 is the default timeout of provider
+	seconds := int((timeout + time.Second - 1) / time.Second)
+	l.Provider.Set(key, entry.String(), seconds+l.StaleTTL)
+}
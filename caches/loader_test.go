@@ -0,0 +1,250 @@
+package caches
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip"
+)
+
+type loaded struct {
+	Id   int
+	Name string
+}
+
+func newTestLoader(t *testing.T, config LoaderConfig) *Loader {
+	if config.Provider == nil {
+		config.Provider, _ = NewMemoryProvider(MemoryConfig{})
+	}
+	loader, err := NewLoader(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loader
+}
+
+func Test_LoaderCollapse(t *testing.T) {
+	loader := newTestLoader(t, LoaderConfig{})
+
+	var calls int32
+	load := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return loaded{Id: 1, Name: "one"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v loaded
+			assert.NoError(t, loader.GetOrLoad("key", 10, &v, load))
+			assert.Equal(t, loaded{Id: 1, Name: "one"}, v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls)
+
+	var v loaded
+	assert.NoError(t, loader.GetOrLoad("key", 10, &v, load))
+	assert.Equal(t, "one", v.Name)
+	assert.Equal(t, int32(1), calls)
+
+	// load error is not cached
+	errLoad := errors.New("load")
+	assert.Equal(t, errLoad, loader.GetOrLoad("failed", 10, &v, func() (interface{}, error) {
+		return nil, errLoad
+	}))
+	assert.NoError(t, loader.GetOrLoad("failed", 10, &v, load))
+}
+
+func Test_LoaderNegative(t *testing.T) {
+	loader := newTestLoader(t, LoaderConfig{NegativeTTL: 10})
+
+	var calls int32
+	load := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, ErrMissedKey
+	}
+
+	var v loaded
+	assert.Equal(t, ErrMissedKey, loader.GetOrLoad("key", 10, &v, load))
+	assert.Equal(t, ErrMissedKey, loader.GetOrLoad("key", 10, &v, load))
+	assert.Equal(t, int32(1), calls)
+}
+
+func Test_LoaderStale(t *testing.T) {
+	now := time.Now()
+	var mu sync.Mutex
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	provider, _ := NewMemoryProvider(MemoryConfig{})
+	provider.(*memoryProvide).now = clock
+	loader := newTestLoader(t, LoaderConfig{Provider: provider, StaleTTL: 10})
+	loader.now = clock
+
+	var calls int32
+	load := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		return loaded{Id: int(n)}, nil
+	}
+
+	var v loaded
+	assert.NoError(t, loader.GetOrLoad("key", 1, &v, load))
+	assert.Equal(t, 1, v.Id)
+
+	mu.Lock()
+	now = now.Add(2 * time.Second)
+	mu.Unlock()
+
+	// stale value is served while refreshing
+	assert.NoError(t, loader.GetOrLoad("key", 1, &v, load))
+	assert.Equal(t, 1, v.Id)
+
+	for i := 0; i < 100 && atomic.LoadInt32(&calls) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, loader.GetOrLoad("key", 1, &v, load))
+	assert.Equal(t, 2, v.Id)
+
+	// expired after stale ttl
+	mu.Lock()
+	now = now.Add(20 * time.Second)
+	mu.Unlock()
+	assert.NoError(t, loader.GetOrLoad("key", 1, &v, load))
+	assert.Equal(t, 3, v.Id)
+}
+
+func Test_LoaderLock(t *testing.T) {
+	provider, _ := NewMemoryProvider(MemoryConfig{})
+	a := newTestLoader(t, LoaderConfig{Provider: provider, Lock: true})
+	b := newTestLoader(t, LoaderConfig{Provider: provider, Lock: true})
+
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var v loaded
+		assert.NoError(t, a.GetOrLoad("key", 10, &v, func() (interface{}, error) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return loaded{Id: 1}, nil
+		}))
+	}()
+	<-started
+
+	// waits for the lock holder instead of loading
+	var v loaded
+	assert.NoError(t, b.GetOrLoad("key", 10, &v, func() (interface{}, error) {
+		return loaded{Id: 2}, nil
+	}))
+	assert.Equal(t, 1, v.Id)
+	<-done
+
+	// released to tombstone
+	lock, _ := provider.Get("key" + LockSuffix)
+	assert.Equal(t, "", lock.String())
+
+	_, err := NewLoader(LoaderConfig{Provider: struct{ CacheProvider }{provider}, Lock: true})
+	assert.Equal(t, ErrLoaderLock, err)
+}
+
+func Test_LoaderLockExpired(t *testing.T) {
+	memory := newTestMemory()
+	providers := map[string]CacheProvider{
+		"cas": newTestMemory(),
+		// without CASProvider
+		"nx": struct {
+			NXProvider
+			CacheProvider
+		}{memory.(NXProvider), memory},
+	}
+
+	for name, provider := range providers {
+		loader := newTestLoader(t, LoaderConfig{Provider: provider, Lock: true})
+
+		var v loaded
+		assert.NoError(t, loader.GetOrLoad("key", 10, &v, func() (interface{}, error) {
+			// lock expired and taken by others while loading
+			provider.Set("key"+LockSuffix, "others", 10)
+			return loaded{Id: 1}, nil
+		}), name)
+
+		lock, _ := provider.Get("key" + LockSuffix)
+		assert.Equal(t, "others", lock.String(), name)
+	}
+}
+
+func Test_LoaderRefreshPanic(t *testing.T) {
+	now := time.Now()
+	var mu sync.Mutex
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+
+	var buf bytes.Buffer
+	var bufMu sync.Mutex
+	logger := strip.NewLogger(log.New(writerFunc(func(p []byte) (int, error) {
+		bufMu.Lock()
+		defer bufMu.Unlock()
+		return buf.Write(p)
+	}), "", 0))
+
+	provider, _ := NewMemoryProvider(MemoryConfig{})
+	provider.(*memoryProvide).now = clock
+	loader := newTestLoader(t, LoaderConfig{Provider: provider, StaleTTL: 10, Logger: logger})
+	loader.now = clock
+
+	var v loaded
+	assert.NoError(t, loader.GetOrLoad("key", 1, &v, func() (interface{}, error) {
+		return loaded{Id: 1}, nil
+	}))
+
+	mu.Lock()
+	now = now.Add(2 * time.Second)
+	mu.Unlock()
+
+	panicked := make(chan struct{})
+	assert.NoError(t, loader.GetOrLoad("key", 1, &v, func() (interface{}, error) {
+		defer close(panicked)
+		panic("boom")
+	}))
+	<-panicked
+	time.Sleep(20 * time.Millisecond)
+
+	// stale value is kept
+	assert.NoError(t, loader.GetOrLoad("key", 1, &v, func() (interface{}, error) {
+		return loaded{Id: 2}, nil
+	}))
+	assert.Equal(t, 1, v.Id)
+
+	bufMu.Lock()
+	assert.True(t, strings.Contains(buf.String(), "boom"), buf.String())
+	bufMu.Unlock()
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func newTestMemory() CacheProvider {
+	provider, _ := NewMemoryProvider(MemoryConfig{})
+	return provider
+}
@@ -0,0 +1,72 @@
+package lock
+
+import (
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+type mcBackend struct {
+	client *memcache.Client
+}
+
+var _ Backend = new(mcBackend)
+
+// NewMcBackend acquires by memcache add, renews and releases by cas,
+// ttl is rounded up to seconds
+func NewMcBackend(client *memcache.Client) Backend {
+	return &mcBackend{client: client}
+}
+
+func (b *mcBackend) Acquire(key, token string, ttl time.Duration) (bool, error) {
+	err := b.client.Add(&memcache.Item{
+		Key:        key,
+		Value:      []byte(token),
+		Expiration: seconds(ttl),
+	})
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *mcBackend) Renew(key, token string, ttl time.Duration) (bool, error) {
+	return b.swap(key, token, seconds(ttl))
+}
+
+// Release swaps the lock with negative expiration, memcache has no conditional delete
+func (b *mcBackend) Release(key, token string) (bool, error) {
+	return b.swap(key, token, -1)
+}
+
+func (b *mcBackend) swap(key, token string, expiration int32) (bool, error) {
+	item, err := b.client.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if string(item.Value) != token {
+		return false, nil
+	}
+
+	item.Expiration = expiration
+	err = b.client.CompareAndSwap(item)
+	switch err {
+	case nil:
+		return true, nil
+	case memcache.ErrCASConflict, memcache.ErrNotStored, memcache.ErrCacheMiss:
+		return false, nil
+	}
+	return false, err
+}
+
+// seconds rounds ttl up, memcache treats 0 as never expire
+func seconds(ttl time.Duration) int32 {
+	s := int32((ttl + time.Second - 1) / time.Second)
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+package lock
+
+import (
+	"time"
+
+	"github.com/wujiu2020/strip/caches"
+)
+
+// memoryProvider is the memory provider of caches with its NX and CAS capabilities
+type memoryProvider interface {
+	caches.CacheProvider
+	caches.NXProvider
+	caches.CASProvider
+}
+
+// releasedToken is the tombstone of released lock, tokens are never empty
+const releasedToken = ""
+
+// memoryBackend holds locks in process, for tests and single instance
+type memoryBackend struct {
+	provider memoryProvider
+}
+
+var _ Backend = new(memoryBackend)
+
+// NewMemoryBackend acquires by SetNX and renews and releases by CAS of caches.NewMemoryProvider,
+// ttl is rounded up to seconds
+func NewMemoryBackend() Backend {
+	provider, _ := caches.NewMemoryProvider(caches.MemoryConfig{})
+	return &memoryBackend{provider: provider.(memoryProvider)}
+}
+
+// Acquire takes the key if it's missed, or released to the tombstone
+func (b *memoryBackend) Acquire(key, token string, ttl time.Duration) (bool, error) {
+	ok, err := b.provider.SetNX(key, token, int(seconds(ttl)))
+	if ok || err != nil {
+		return ok, err
+	}
+	return b.swap(key, releasedToken, token, int(seconds(ttl)))
+}
+
+func (b *memoryBackend) Renew(key, token string, ttl time.Duration) (bool, error) {
+	return b.swap(key, token, token, int(seconds(ttl)))
+}
+
+// Release swaps the lock with the tombstone expires in a second, the provider has no
+// conditional delete, and Delete after the swap may remove the lock of next holder
+func (b *memoryBackend) Release(key, token string) (bool, error) {
+	return b.swap(key, token, releasedToken, 1)
+}
+
+func (b *memoryBackend) swap(key, token, value string, timeout int) (bool, error) {
+	ok, err := b.provider.CAS(key, token, value, timeout)
+	if err == caches.ErrMissedKey {
+		return false, nil
+	}
+	return ok, err
+}
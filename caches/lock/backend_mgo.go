@@ -0,0 +1,112 @@
+package lock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type MgoConfig struct {
+	KeyField       string
+	TokenField     string
+	ExpiredAtField string
+	Connect        func(f func(c *mgo.Collection) error) error
+}
+
+type mgoBackend struct {
+	config *MgoConfig
+}
+
+var _ Backend = new(mgoBackend)
+
+// NewMgoBackend acquires by insert with unique index of KeyField,
+// expired locks are replaced on acquire and removed by mongo TTL index
+func NewMgoBackend(config MgoConfig) (Backend, error) {
+	err := config.Connect(func(c *mgo.Collection) (err error) {
+		// unique index of key field
+		if config.KeyField != "_id" {
+			if err = c.EnsureIndex(mgo.Index{Key: []string{config.KeyField}, Name: config.KeyField, Unique: true}); err != nil {
+				return fmt.Errorf("NewMgoBackend, EnsureIndex: %v", err)
+			}
+		}
+
+		if err = c.EnsureIndex(mgo.Index{
+			Name:        config.ExpiredAtField,
+			Key:         []string{config.ExpiredAtField},
+			ExpireAfter: time.Minute,
+		}); err != nil {
+			return fmt.Errorf("NewMgoBackend, EnsureIndex: %v", err)
+		}
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mgoBackend{config: &config}, nil
+}
+
+func (b *mgoBackend) Acquire(key, token string, ttl time.Duration) (ok bool, err error) {
+	err = b.config.Connect(func(c *mgo.Collection) error {
+		now := time.Now()
+		doc := bson.M{
+			b.config.KeyField:       key,
+			b.config.TokenField:     token,
+			b.config.ExpiredAtField: now.Add(ttl),
+		}
+
+		// replace expired lock not removed yet
+		er := c.Update(bson.M{
+			b.config.KeyField: key,
+			b.config.ExpiredAtField: bson.M{
+				"$lte": now,
+			},
+		}, doc)
+		if er != mgo.ErrNotFound {
+			return er
+		}
+		return c.Insert(doc)
+	})
+	if mgo.IsDup(err) {
+		return false, nil
+	}
+	ok = err == nil
+	return
+}
+
+func (b *mgoBackend) Renew(key, token string, ttl time.Duration) (bool, error) {
+	err := b.config.Connect(func(c *mgo.Collection) error {
+		now := time.Now()
+		return c.Update(b.held(key, token, now), bson.M{
+			"$set": bson.M{
+				b.config.ExpiredAtField: now.Add(ttl),
+			},
+		})
+	})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *mgoBackend) Release(key, token string) (bool, error) {
+	err := b.config.Connect(func(c *mgo.Collection) error {
+		return c.Remove(b.held(key, token, time.Now()))
+	})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// held selects alive lock of key with token
+func (b *mgoBackend) held(key, token string, now time.Time) bson.M {
+	return bson.M{
+		b.config.KeyField:   key,
+		b.config.TokenField: token,
+		b.config.ExpiredAtField: bson.M{
+			"$gt": now,
+		},
+	}
+}
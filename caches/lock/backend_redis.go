@@ -0,0 +1,72 @@
+package lock
+
+import (
+	"time"
+
+	"github.com/mediocregopher/radix.v2/pool"
+	"github.com/mediocregopher/radix.v2/redis"
+)
+
+const (
+	// RedisRenewScript resets ttl of KEYS[1] in milliseconds ARGV[2] if its value is token ARGV[1]
+	RedisRenewScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+
+	// RedisReleaseScript deletes KEYS[1] if its value is token ARGV[1]
+	RedisReleaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+)
+
+type redisBackend struct {
+	client *pool.Pool
+}
+
+var _ Backend = new(redisBackend)
+
+// NewRedisBackend acquires by SET NX PX, renews and releases by lua scripts
+func NewRedisBackend(client *pool.Pool) Backend {
+	return &redisBackend{client: client}
+}
+
+func (b *redisBackend) Acquire(key, token string, ttl time.Duration) (ok bool, err error) {
+	conn, err := b.client.Get()
+	if err != nil {
+		return
+	}
+	defer b.client.Put(conn)
+
+	resp := conn.Cmd("SET", key, token, "PX", milliseconds(ttl), "NX")
+	if resp.IsType(redis.Nil) {
+		return
+	}
+	err = resp.Err
+	ok = err == nil
+	return
+}
+
+func (b *redisBackend) Renew(key, token string, ttl time.Duration) (bool, error) {
+	return b.eval(RedisRenewScript, key, token, milliseconds(ttl))
+}
+
+func (b *redisBackend) Release(key, token string) (bool, error) {
+	return b.eval(RedisReleaseScript, key, token)
+}
+
+func (b *redisBackend) eval(script, key string, args ...interface{}) (ok bool, err error) {
+	conn, err := b.client.Get()
+	if err != nil {
+		return
+	}
+	defer b.client.Put(conn)
+
+	n, err := conn.Cmd("EVAL", append([]interface{}{script, 1, key}, args...)...).Int()
+	ok = n == 1
+	return
+}
+
+// milliseconds rounds ttl up, redis rejects 0
+func milliseconds(ttl time.Duration) int64 {
+	ms := int64((ttl + time.Millisecond - 1) / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	return ms
+}
@@ -0,0 +1,193 @@
+// Package lock provides distributed lock on the backends of caches.
+package lock
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/wujiu2020/strip/utils"
+)
+
+const (
+	DefaultMinBackoff = 10 * time.Millisecond
+	DefaultMaxBackoff = 500 * time.Millisecond
+
+	tokenSize = 32
+)
+
+var (
+	ErrBackend     = errors.New("lock needs backend")
+	ErrNotAcquired = errors.New("lock is held by others")
+	ErrNotHeld     = errors.New("lock is not held")
+)
+
+// Backend stores lock of key with the token of holder
+type Backend interface {
+	Acquire(key, token string, ttl time.Duration) (bool, error) // set token of key if not exists, reports whether it's acquired
+	Renew(key, token string, ttl time.Duration) (bool, error)   // reset ttl of key if token matches, reports whether it's held
+	Release(key, token string) (bool, error)                    // delete key if token matches, reports whether it's held
+}
+
+type Config struct {
+	Backend    Backend
+	KeyPrefix  string
+	MinBackoff time.Duration // first wait of blocking Acquire, DefaultMinBackoff if 0
+	MaxBackoff time.Duration // max wait of blocking Acquire, DefaultMaxBackoff if 0
+	AutoRenew  bool          // renew acquired lock every 1/3 of ttl until released
+}
+
+type Locker struct {
+	Config
+}
+
+func NewLocker(config Config) (*Locker, error) {
+	if config.Backend == nil {
+		return nil, ErrBackend
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+
+	locker := new(Locker)
+	locker.Config = config
+	return locker, nil
+}
+
+// TryAcquire acquires lock of key expires in ttl, returns ErrNotAcquired if held by others
+func (l *Locker) TryAcquire(key string, ttl time.Duration) (*Lock, error) {
+	token, err := utils.RandomCreateString(tokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := l.Backend.Acquire(l.KeyPrefix+key, token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+
+	lk := &Lock{
+		Key:    key,
+		Token:  token,
+		locker: l,
+		ttl:    ttl,
+		stop:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	if l.AutoRenew {
+		lk.done = make(chan struct{})
+		go lk.renewLoop()
+	}
+	return lk, nil
+}
+
+// Acquire blocks until lock of key acquired with exponential backoff, or ctx done
+func (l *Locker) Acquire(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	backoff := l.MinBackoff
+	for {
+		lk, err := l.TryAcquire(key, ttl)
+		if err != ErrNotAcquired {
+			return lk, err
+		}
+
+		// wait in [backoff/2, backoff) to spread the waiters
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > l.MaxBackoff {
+			backoff = l.MaxBackoff
+		}
+	}
+}
+
+// Lock is the acquired lock, Token identifies the holder
+type Lock struct {
+	Key   string
+	Token string
+
+	locker *Locker
+	ttl    time.Duration
+
+	once sync.Once
+	stop chan struct{}
+	done chan struct{} // closed when auto renewal stopped
+	lost chan struct{}
+}
+
+// Renew resets ttl of the lock, returns ErrNotHeld if it's expired or held by others
+func (lk *Lock) Renew() error {
+	held, err := lk.locker.Backend.Renew(lk.locker.KeyPrefix+lk.Key, lk.Token, lk.ttl)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Release stops renewal and deletes the lock if still held,
+// returns ErrNotHeld if it's expired or held by others
+func (lk *Lock) Release() error {
+	lk.once.Do(func() {
+		close(lk.stop)
+	})
+	if lk.done != nil {
+		// renewal in flight would conflict with release
+		<-lk.done
+	}
+
+	held, err := lk.locker.Backend.Release(lk.locker.KeyPrefix+lk.Key, lk.Token)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Lost is closed when auto renewal finds the lock is not held
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+func (lk *Lock) renewLoop() {
+	interval := lk.ttl / 3
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(lk.done)
+
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-ticker.C:
+			// errors of backend are retried on next tick
+			if err := lk.Renew(); err == ErrNotHeld {
+				close(lk.lost)
+				return
+			}
+		}
+	}
+}
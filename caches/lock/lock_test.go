@@ -0,0 +1,177 @@
+package lock
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/mediocregopher/radix.v2/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip/caches/cachestest"
+)
+
+// testBackends returns backends with the min ttl they support
+func testBackends(t *testing.T) (map[string]Backend, map[string]time.Duration, func()) {
+	mc, err := cachestest.NewMemcacheServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := cachestest.NewRedisServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.RegisterScript(RedisRenewScript, func(db *cachestest.RedisDB, keys, args []string) interface{} {
+		if v, ok := db.Get(keys[0]); ok && v == args[0] {
+			ms, _ := strconv.Atoi(args[1])
+			db.Expire(keys[0], time.Duration(ms)*time.Millisecond)
+			return 1
+		}
+		return 0
+	})
+	rs.RegisterScript(RedisReleaseScript, func(db *cachestest.RedisDB, keys, args []string) interface{} {
+		if v, ok := db.Get(keys[0]); ok && v == args[0] {
+			db.Del(keys[0])
+			return 1
+		}
+		return 0
+	})
+	client, err := pool.New("tcp", rs.Addr(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backends := map[string]Backend{
+		"memory":   NewMemoryBackend(),
+		"memcache": NewMcBackend(memcache.New(mc.Addr())),
+		"redis":    NewRedisBackend(client),
+	}
+	ttls := map[string]time.Duration{
+		"memory":   time.Second,
+		"memcache": time.Second,
+		"redis":    100 * time.Millisecond,
+	}
+	return backends, ttls, func() {
+		client.Empty()
+		mc.Close()
+		rs.Close()
+	}
+}
+
+func TestLock(t *testing.T) {
+	backends, ttls, closeAll := testBackends(t)
+	defer closeAll()
+
+	for name, backend := range backends {
+		ttl := ttls[name]
+		t.Run(name, func(t *testing.T) {
+			locker, err := NewLocker(Config{Backend: backend, KeyPrefix: "lock:"})
+			assert.NoError(t, err)
+
+			lk, err := locker.TryAcquire("key", 10*time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, "key", lk.Key)
+
+			_, err = locker.TryAcquire("key", 10*time.Second)
+			assert.Equal(t, ErrNotAcquired, err)
+
+			// token of others doesn't release
+			other := &Lock{Key: "key", Token: "other", locker: locker, stop: make(chan struct{})}
+			assert.Equal(t, ErrNotHeld, other.Release())
+			assert.Equal(t, ErrNotHeld, other.Renew())
+
+			assert.NoError(t, lk.Renew())
+			assert.NoError(t, lk.Release())
+			assert.Equal(t, ErrNotHeld, lk.Release())
+
+			lk, err = locker.TryAcquire("key", 10*time.Second)
+			assert.NoError(t, err)
+			assert.NoError(t, lk.Release())
+
+			// expired lock can be acquired
+			lk, err = locker.TryAcquire("expired", ttl)
+			assert.NoError(t, err)
+			time.Sleep(2*ttl + 100*time.Millisecond)
+			lk2, err := locker.TryAcquire("expired", 10*time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, ErrNotHeld, lk.Release())
+			assert.NoError(t, lk2.Release())
+		})
+	}
+}
+
+func TestLockAcquire(t *testing.T) {
+	backends, _, closeAll := testBackends(t)
+	defer closeAll()
+
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			locker, err := NewLocker(Config{Backend: backend, MaxBackoff: 20 * time.Millisecond})
+			assert.NoError(t, err)
+
+			// waiters run one by one
+			var (
+				wg      sync.WaitGroup
+				holders int32
+			)
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					lk, err := locker.Acquire(context.Background(), "key", 10*time.Second)
+					if !assert.NoError(t, err) {
+						return
+					}
+					assert.Equal(t, int32(1), atomic.AddInt32(&holders, 1))
+					time.Sleep(10 * time.Millisecond)
+					atomic.AddInt32(&holders, -1)
+					assert.NoError(t, lk.Release())
+				}()
+			}
+			wg.Wait()
+
+			lk, err := locker.TryAcquire("key", 10*time.Second)
+			assert.NoError(t, err)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err = locker.Acquire(ctx, "key", 10*time.Second)
+			assert.Equal(t, context.DeadlineExceeded, err)
+			assert.NoError(t, lk.Release())
+		})
+	}
+}
+
+func TestLockAutoRenew(t *testing.T) {
+	backends, ttls, closeAll := testBackends(t)
+	defer closeAll()
+
+	for name, backend := range backends {
+		ttl := ttls[name]
+		t.Run(name, func(t *testing.T) {
+			locker, err := NewLocker(Config{Backend: backend, AutoRenew: true})
+			assert.NoError(t, err)
+
+			lk, err := locker.TryAcquire("key", ttl)
+			assert.NoError(t, err)
+			time.Sleep(ttl * 3)
+			_, err = locker.TryAcquire("key", ttl)
+			assert.Equal(t, ErrNotAcquired, err, "renewed")
+			assert.NoError(t, lk.Release())
+
+			// lost by others release
+			lk, err = locker.TryAcquire("key", ttl)
+			assert.NoError(t, err)
+			_, err = backend.Release("key", lk.Token)
+			assert.NoError(t, err)
+			select {
+			case <-lk.Lost():
+			case <-time.After(ttl * 2):
+				t.Error("lost is not notified")
+			}
+			assert.Equal(t, ErrNotHeld, lk.Release())
+		})
+	}
+}
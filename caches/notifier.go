@@ -0,0 +1,59 @@
+package caches
+
+import (
+	"sync"
+)
+
+// Invalidation notifies instances to drop keys of their local cache
+type Invalidation struct {
+	Origin string   `json:"origin"` // id of the publisher, which ignores it
+	Keys   []string `json:"keys,omitempty"`
+	All    bool     `json:"all,omitempty"` // drop all keys, eg: messages may be lost
+}
+
+// Notifier broadcasts invalidations between instances
+type Notifier interface {
+	Publish(inv Invalidation) error
+	Subscribe(fn func(inv Invalidation)) (cancel func(), err error)
+}
+
+// memoryNotifier broadcasts in process, for tests
+type memoryNotifier struct {
+	mu   sync.Mutex
+	seq  int
+	subs map[int]func(inv Invalidation)
+}
+
+var _ Notifier = new(memoryNotifier)
+
+func NewMemoryNotifier() Notifier {
+	return &memoryNotifier{subs: make(map[int]func(inv Invalidation))}
+}
+
+func (n *memoryNotifier) Publish(inv Invalidation) error {
+	n.mu.Lock()
+	subs := make([]func(inv Invalidation), 0, len(n.subs))
+	for _, fn := range n.subs {
+		subs = append(subs, fn)
+	}
+	n.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(inv)
+	}
+	return nil
+}
+
+func (n *memoryNotifier) Subscribe(fn func(inv Invalidation)) (func(), error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.seq++
+	id := n.seq
+	n.subs[id] = fn
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		delete(n.subs, id)
+	}, nil
+}
@@ -0,0 +1,148 @@
+package caches
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mediocregopher/radix.v2/pool"
+	"github.com/mediocregopher/radix.v2/pubsub"
+	"github.com/mediocregopher/radix.v2/redis"
+)
+
+const DefaultNotifyChannel = "caches:invalidation"
+
+var errSubscriberClosed = errors.New("subscriber closed")
+
+type RedisNotifierConfig struct {
+	Client  *pool.Pool
+	Channel string // DefaultNotifyChannel if empty
+}
+
+// redisNotifier broadcasts by redis pub/sub
+type redisNotifier struct {
+	RedisNotifierConfig
+}
+
+var _ Notifier = new(redisNotifier)
+
+func NewRedisNotifier(config RedisNotifierConfig) (Notifier, error) {
+	if config.Channel == "" {
+		config.Channel = DefaultNotifyChannel
+	}
+	notifier := new(redisNotifier)
+	notifier.RedisNotifierConfig = config
+	return notifier, nil
+}
+
+func (n *redisNotifier) Publish(inv Invalidation) (err error) {
+	data, err := json.Marshal(inv)
+	if err != nil {
+		return
+	}
+
+	conn, err := n.Client.Get()
+	if err != nil {
+		return
+	}
+	defer n.Client.Put(conn)
+
+	err = conn.Cmd("PUBLISH", n.Channel, data).Err
+	return
+}
+
+// Subscribe holds a connection of the pool, and reconnects if it's broken.
+// fn is called with All invalidation after reconnected, messages may be lost meanwhile.
+func (n *redisNotifier) Subscribe(fn func(inv Invalidation)) (func(), error) {
+	sub := &redisSubscriber{notifier: n, fn: fn, closed: make(chan struct{})}
+	conn, err := sub.subscribe()
+	if err != nil {
+		return nil, err
+	}
+	go sub.receive(conn)
+	return sub.close, nil
+}
+
+type redisSubscriber struct {
+	notifier *redisNotifier
+	fn       func(inv Invalidation)
+
+	mu     sync.Mutex
+	conn   *redis.Client
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (s *redisSubscriber) subscribe() (*pubsub.SubClient, error) {
+	conn, err := s.notifier.Client.Get()
+	if err != nil {
+		return nil, err
+	}
+	sub := pubsub.NewSubClient(conn)
+	if resp := sub.Subscribe(s.notifier.Channel); resp.Err != nil {
+		conn.Close()
+		return nil, resp.Err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.closed:
+		conn.Close()
+		return nil, errSubscriberClosed
+	default:
+	}
+	s.conn = conn
+	return sub, nil
+}
+
+func (s *redisSubscriber) receive(sub *pubsub.SubClient) {
+	for {
+		resp := sub.Receive()
+		switch resp.Type {
+		case pubsub.Message:
+			var inv Invalidation
+			if json.Unmarshal([]byte(resp.Message), &inv) == nil {
+				s.fn(inv)
+			}
+			continue
+		case pubsub.Error:
+		default:
+			continue
+		}
+
+		// reconnect until closed
+		s.mu.Lock()
+		s.conn.Close()
+		s.mu.Unlock()
+		backoff := 100 * time.Millisecond
+		for {
+			select {
+			case <-s.closed:
+				return
+			case <-time.After(backoff):
+			}
+
+			var err error
+			if sub, err = s.subscribe(); err == nil {
+				s.fn(Invalidation{All: true})
+				break
+			}
+			if backoff < 5*time.Second {
+				backoff *= 2
+			}
+		}
+	}
+}
+
+func (s *redisSubscriber) close() {
+	s.once.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.closed)
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	})
+}
@@ -14,7 +14,13 @@ type mcProvide struct {
 	McConfig
 }
 
-var _ CacheProvider = new(mcProvide)
+var (
+	_ CacheProvider   = new(mcProvide)
+	_ BatchProvider   = new(mcProvide)
+	_ NXProvider      = new(mcProvide)
+	_ CounterProvider = new(mcProvide)
+	_ CASProvider     = new(mcProvide)
+)
 
 func NewMcProvider(config McConfig) (sess CacheProvider, err error) {
 	provider := new(mcProvide)
@@ -81,6 +87,100 @@ func (p *mcProvide) Decr(key string, params ...int) (err error) {
 	return
 }
 
+// IncrBy returns ErrMissedKey of missed key, memcache decr is capped at 0
+func (p *mcProvide) IncrBy(key string, delta int64) (n int64, err error) {
+	key = p.KeyPrefix + key
+	var v uint64
+	if delta < 0 {
+		v, err = p.Client.Decrement(key, uint64(-delta))
+	} else {
+		v, err = p.Client.Increment(key, uint64(delta))
+	}
+	if err != nil {
+		err = p.handleError(err)
+		return
+	}
+	n = int64(v)
+	return
+}
+
+func (p *mcProvide) DecrBy(key string, delta int64) (int64, error) {
+	return p.IncrBy(key, -delta)
+}
+
+// SetNX maps to memcache add
+func (p *mcProvide) SetNX(key string, val interface{}, params ...int) (bool, error) {
+	key = p.KeyPrefix + key
+	timeout := getTimeoutDur(params...)
+	err := p.Client.Add(&memcache.Item{
+		Key:        key,
+		Value:      []byte(utils.ToStr(val)),
+		Expiration: int32(timeout.Seconds()),
+	})
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CAS compares string form of values, and swaps with the cas token of memcache
+func (p *mcProvide) CAS(key string, old, new interface{}, params ...int) (bool, error) {
+	key = p.KeyPrefix + key
+	timeout := getTimeoutDur(params...)
+
+	item, err := p.Client.Get(key)
+	if err != nil {
+		return false, p.handleError(err)
+	}
+	if string(item.Value) != utils.ToStr(old) {
+		return false, nil
+	}
+
+	item.Value = []byte(utils.ToStr(new))
+	item.Expiration = int32(timeout.Seconds())
+	err = p.Client.CompareAndSwap(item)
+	if err == memcache.ErrCASConflict || err == memcache.ErrNotStored {
+		return false, nil
+	}
+	return err == nil, p.handleError(err)
+}
+
+func (p *mcProvide) GetMulti(keys []string) (map[string]utils.StrTo, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = p.KeyPrefix + key
+	}
+
+	items, err := p.Client.GetMulti(prefixed)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]utils.StrTo, len(items))
+	for key, item := range items {
+		values[key[len(p.KeyPrefix):]] = utils.StrTo(string(item.Value))
+	}
+	return values, nil
+}
+
+// SetMulti sets keys one by one, memcache has no multi set command
+func (p *mcProvide) SetMulti(values map[string]interface{}, params ...int) error {
+	for key, val := range values {
+		if err := p.Set(key, val, params...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *mcProvide) DeleteMulti(keys []string) error {
+	for _, key := range keys {
+		if err := p.Client.Delete(p.KeyPrefix + key); err != nil && err != memcache.ErrCacheMiss {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *mcProvide) Has(key string) (bool, error) {
 	key = p.KeyPrefix + key
 	_, err := p.Client.Get(key)
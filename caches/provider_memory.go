@@ -0,0 +1,372 @@
+package caches
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wujiu2020/strip/utils"
+)
+
+type MemoryConfig struct {
+	MaxEntries int   // max count of keys, unlimited if 0
+	MaxBytes   int64 // max bytes of keys and values, unlimited if 0
+}
+
+type memoryEntry struct {
+	key       string
+	value     string
+	expiredAt time.Time // zero if never expire
+}
+
+func (e *memoryEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiredAt.IsZero() && !now.Before(e.expiredAt)
+}
+
+// memoryProvide is in-process cache, least recently used keys are evicted
+// when MaxEntries or MaxBytes exceeded
+type memoryProvide struct {
+	MemoryConfig
+
+	mu    sync.Mutex
+	ll    *list.List // front is the most recently used
+	items map[string]*list.Element
+	bytes int64
+
+	now func() time.Time
+}
+
+var (
+	_ CacheProvider    = new(memoryProvide)
+	_ NXProvider       = new(memoryProvide)
+	_ BatchProvider    = new(memoryProvide)
+	_ CounterProvider  = new(memoryProvide)
+	_ CASProvider      = new(memoryProvide)
+	_ TTLProvider      = new(memoryProvide)
+	_ BatchTTLProvider = new(memoryProvide)
+	_ ScanProvider     = new(memoryProvide)
+)
+
+func NewMemoryProvider(config MemoryConfig) (prov CacheProvider, err error) {
+	provider := new(memoryProvide)
+	provider.MemoryConfig = config
+	provider.ll = list.New()
+	provider.items = make(map[string]*list.Element)
+	provider.now = time.Now
+	return provider, nil
+}
+
+func (p *memoryProvide) Get(key string) (value utils.StrTo, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.get(key)
+	if !ok {
+		err = ErrMissedKey
+		return
+	}
+	value = utils.StrTo(e.value)
+	return
+}
+
+func (p *memoryProvide) Set(key string, val interface{}, params ...int) (err error) {
+	timeout := getTimeoutDur(params...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry := &memoryEntry{key: key, value: utils.ToStr(val)}
+	if timeout > 0 {
+		entry.expiredAt = p.now().Add(timeout)
+	}
+	p.set(entry)
+	return
+}
+
+func (p *memoryProvide) SetNX(key string, val interface{}, params ...int) (bool, error) {
+	timeout := getTimeoutDur(params...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.get(key); ok {
+		return false, nil
+	}
+	entry := &memoryEntry{key: key, value: utils.ToStr(val)}
+	if timeout > 0 {
+		entry.expiredAt = p.now().Add(timeout)
+	}
+	p.set(entry)
+	return true, nil
+}
+
+func (p *memoryProvide) Touch(key string, params ...int) (err error) {
+	timeout := getTimeoutDur(params...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.get(key)
+	if !ok {
+		return ErrMissedKey
+	}
+	e.expiredAt = time.Time{}
+	if timeout > 0 {
+		e.expiredAt = p.now().Add(timeout)
+	}
+	return
+}
+
+func (p *memoryProvide) Delete(key string) (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.get(key); !ok {
+		return ErrMissedKey
+	}
+	p.remove(p.items[key])
+	return
+}
+
+func (p *memoryProvide) Incr(key string, params ...int) (err error) {
+	cnt := 1
+	if len(params) > 0 {
+		cnt = params[0]
+	}
+	_, err = p.incrBy(key, int64(cnt))
+	return
+}
+
+func (p *memoryProvide) Decr(key string, params ...int) (err error) {
+	cnt := 1
+	if len(params) > 0 {
+		cnt = params[0]
+	}
+	_, err = p.incrBy(key, -int64(cnt))
+	return
+}
+
+func (p *memoryProvide) GetMulti(keys []string) (map[string]utils.StrTo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	values := make(map[string]utils.StrTo, len(keys))
+	for _, key := range keys {
+		if e, ok := p.get(key); ok {
+			values[key] = utils.StrTo(e.value)
+		}
+	}
+	return values, nil
+}
+
+func (p *memoryProvide) SetMulti(values map[string]interface{}, params ...int) error {
+	timeout := getTimeoutDur(params...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, val := range values {
+		entry := &memoryEntry{key: key, value: utils.ToStr(val)}
+		if timeout > 0 {
+			entry.expiredAt = p.now().Add(timeout)
+		}
+		p.set(entry)
+	}
+	return nil
+}
+
+func (p *memoryProvide) DeleteMulti(keys []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := p.items[key]; ok {
+			p.remove(elem)
+		}
+	}
+	return nil
+}
+
+func (p *memoryProvide) IncrBy(key string, delta int64) (int64, error) {
+	return p.incrBy(key, delta)
+}
+
+func (p *memoryProvide) DecrBy(key string, delta int64) (int64, error) {
+	return p.incrBy(key, -delta)
+}
+
+// CAS compares string form of values
+func (p *memoryProvide) CAS(key string, old, new interface{}, params ...int) (bool, error) {
+	timeout := getTimeoutDur(params...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.get(key)
+	if !ok {
+		return false, ErrMissedKey
+	}
+	if e.value != utils.ToStr(old) {
+		return false, nil
+	}
+	entry := &memoryEntry{key: key, value: utils.ToStr(new)}
+	if timeout > 0 {
+		entry.expiredAt = p.now().Add(timeout)
+	}
+	p.set(entry)
+	return true, nil
+}
+
+func (p *memoryProvide) Keys(prefix string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var keys []string
+	now := p.now()
+	for key, elem := range p.items {
+		if strings.HasPrefix(key, prefix) && !elem.Value.(*memoryEntry).expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Scan iterates the snapshot of keys, fn may modify the cache
+func (p *memoryProvide) Scan(prefix string, fn func(key string) error) error {
+	keys, _ := p.Keys(prefix)
+	for _, key := range keys {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *memoryProvide) TTL(key string) (time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.get(key)
+	if !ok {
+		return 0, ErrMissedKey
+	}
+	if e.expiredAt.IsZero() {
+		return -1, nil
+	}
+	return e.expiredAt.Sub(p.now()), nil
+}
+
+func (p *memoryProvide) GetMultiTTL(keys []string) (map[string]TTLValue, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	values := make(map[string]TTLValue, len(keys))
+	for _, key := range keys {
+		e, ok := p.get(key)
+		if !ok {
+			continue
+		}
+		v := TTLValue{Value: utils.StrTo(e.value), TTL: -1}
+		if !e.expiredAt.IsZero() {
+			v.TTL = e.expiredAt.Sub(p.now())
+		}
+		values[key] = v
+	}
+	return values, nil
+}
+
+func (p *memoryProvide) Has(key string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.get(key)
+	return ok, nil
+}
+
+func (p *memoryProvide) Clean() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ll.Init()
+	p.items = make(map[string]*list.Element)
+	p.bytes = 0
+	return nil
+}
+
+// GC sweeps all expired keys
+func (p *memoryProvide) GC() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for elem := p.ll.Back(); elem != nil; {
+		prev := elem.Prev()
+		if elem.Value.(*memoryEntry).expired(now) {
+			p.remove(elem)
+		}
+		elem = prev
+	}
+	return nil
+}
+
+// incrBy adds delta to integer value and keeps the timeout, returns the new value
+func (p *memoryProvide) incrBy(key string, delta int64) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.get(key)
+	if !ok {
+		return 0, ErrMissedKey
+	}
+	n, err := strconv.ParseInt(e.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n += delta
+
+	p.set(&memoryEntry{key: key, value: strconv.FormatInt(n, 10), expiredAt: e.expiredAt})
+	return n, nil
+}
+
+// get returns the alive entry and marks it recently used, expired entry removed
+func (p *memoryProvide) get(key string) (*memoryEntry, bool) {
+	elem, ok := p.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*memoryEntry)
+	if e.expired(p.now()) {
+		p.remove(elem)
+		return nil, false
+	}
+	p.ll.MoveToFront(elem)
+	return e, true
+}
+
+func (p *memoryProvide) set(entry *memoryEntry) {
+	if elem, ok := p.items[entry.key]; ok {
+		p.bytes += entry.size() - elem.Value.(*memoryEntry).size()
+		elem.Value = entry
+		p.ll.MoveToFront(elem)
+	} else {
+		p.items[entry.key] = p.ll.PushFront(entry)
+		p.bytes += entry.size()
+	}
+
+	// evict least recently used, the new entry is kept even if larger than MaxBytes
+	for p.ll.Len() > 1 && (p.MaxEntries > 0 && p.ll.Len() > p.MaxEntries || p.MaxBytes > 0 && p.bytes > p.MaxBytes) {
+		p.remove(p.ll.Back())
+	}
+}
+
+func (p *memoryProvide) remove(elem *list.Element) {
+	e := p.ll.Remove(elem).(*memoryEntry)
+	delete(p.items, e.key)
+	p.bytes -= e.size()
+}
@@ -2,6 +2,7 @@ package caches
 
 import (
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/globalsign/mgo"
@@ -22,7 +23,14 @@ type mgoProvide struct {
 	connect func(func(c *mgo.Collection) error) error
 }
 
-var _ CacheProvider = new(mgoProvide)
+var (
+	_ CacheProvider   = new(mgoProvide)
+	_ BatchProvider   = new(mgoProvide)
+	_ NXProvider      = new(mgoProvide)
+	_ CounterProvider = new(mgoProvide)
+	_ CASProvider     = new(mgoProvide)
+	_ ScanProvider    = new(mgoProvide)
+)
 
 func NewMgoProvider(config MgoConfig) (prov CacheProvider, err error) {
 	provider := new(mgoProvide)
@@ -140,6 +148,9 @@ func (p *mgoProvide) Incr(key string, params ...int) (err error) {
 	err = p.connect(func(c *mgo.Collection) error {
 		er := c.Update(bson.M{
 			p.config.KeyField: key,
+			p.config.ExpiredAtField: bson.M{
+				"$gt": time.Now(),
+			},
 		}, bson.M{
 			"$inc": bson.M{
 				p.config.ValueField: cnt,
@@ -160,6 +171,9 @@ func (p *mgoProvide) Decr(key string, params ...int) (err error) {
 	err = p.connect(func(c *mgo.Collection) error {
 		er := c.Update(bson.M{
 			p.config.KeyField: key,
+			p.config.ExpiredAtField: bson.M{
+				"$gt": time.Now(),
+			},
 		}, bson.M{
 			"$inc": bson.M{
 				p.config.ValueField: cnt,
@@ -171,6 +185,175 @@ func (p *mgoProvide) Decr(key string, params ...int) (err error) {
 	return
 }
 
+func (p *mgoProvide) IncrBy(key string, delta int64) (n int64, err error) {
+	var values map[string]interface{}
+	err = p.connect(func(c *mgo.Collection) error {
+		_, er := c.Find(bson.M{
+			p.config.KeyField: key,
+			p.config.ExpiredAtField: bson.M{
+				"$gt": time.Now(),
+			},
+		}).Apply(mgo.Change{
+			Update: bson.M{
+				"$inc": bson.M{
+					p.config.ValueField: delta,
+				},
+			},
+			ReturnNew: true,
+		}, &values)
+		return er
+	})
+	if err != nil {
+		err = p.handleError(err)
+		return
+	}
+	n, err = utils.ToInt64(values[p.config.ValueField])
+	return
+}
+
+func (p *mgoProvide) DecrBy(key string, delta int64) (int64, error) {
+	return p.IncrBy(key, -delta)
+}
+
+// SetNX replaces expired key, or inserts the key relies on its unique index
+func (p *mgoProvide) SetNX(key string, val interface{}, params ...int) (ok bool, err error) {
+	err = p.connect(func(c *mgo.Collection) error {
+		timeout := getTimeoutDurEx(params...)
+		now := time.Now()
+		doc := bson.M{
+			p.config.KeyField:       key,
+			p.config.ValueField:     val,
+			p.config.ExpiredAtField: now.Add(timeout),
+		}
+
+		er := c.Update(bson.M{
+			p.config.KeyField: key,
+			p.config.ExpiredAtField: bson.M{
+				"$lte": now,
+			},
+		}, doc)
+		if er != mgo.ErrNotFound {
+			return er
+		}
+		return c.Insert(doc)
+	})
+	if mgo.IsDup(err) {
+		return false, nil
+	}
+	ok = err == nil
+	return
+}
+
+// CAS compares string form of values, the cached value is swapped only if
+// it's unchanged after the comparison
+func (p *mgoProvide) CAS(key string, old, new interface{}, params ...int) (ok bool, err error) {
+	values, err := p.fetchValues(key)
+	if err != nil {
+		err = p.handleError(err)
+		return
+	}
+	v, exists := p.getValue(values)
+	if !exists {
+		err = ErrMissedKey
+		return
+	}
+	if utils.ToStr(v) != utils.ToStr(old) {
+		return
+	}
+
+	err = p.connect(func(c *mgo.Collection) error {
+		timeout := getTimeoutDurEx(params...)
+		now := time.Now()
+
+		er := c.Update(bson.M{
+			p.config.KeyField:   key,
+			p.config.ValueField: v,
+			p.config.ExpiredAtField: bson.M{
+				"$gt": now,
+			},
+		}, bson.M{
+			"$set": bson.M{
+				p.config.ValueField:     new,
+				p.config.ExpiredAtField: now.Add(timeout),
+			},
+		})
+		return er
+	})
+	if err == mgo.ErrNotFound {
+		// changed or expired after the comparison
+		err = nil
+		return
+	}
+	ok = err == nil
+	return
+}
+
+func (p *mgoProvide) GetMulti(keys []string) (values map[string]utils.StrTo, err error) {
+	values = make(map[string]utils.StrTo, len(keys))
+	if len(keys) == 0 {
+		return
+	}
+
+	var docs []map[string]interface{}
+	err = p.connect(func(c *mgo.Collection) error {
+		return c.Find(bson.M{
+			p.config.KeyField: bson.M{"$in": keys},
+		}).All(&docs)
+	})
+	if err != nil {
+		return
+	}
+
+	for _, doc := range docs {
+		key, _ := doc[p.config.KeyField].(string)
+		if v, ok := p.getValue(doc); ok {
+			values[key] = utils.StrTo(utils.ToStr(v))
+		}
+	}
+	return
+}
+
+// SetMulti upserts keys in one unordered bulk
+func (p *mgoProvide) SetMulti(values map[string]interface{}, params ...int) (err error) {
+	if len(values) == 0 {
+		return
+	}
+
+	err = p.connect(func(c *mgo.Collection) error {
+		timeout := getTimeoutDurEx(params...)
+		expiredAt := time.Now().Add(timeout)
+
+		bulk := c.Bulk()
+		bulk.Unordered()
+		for key, val := range values {
+			bulk.Upsert(bson.M{
+				p.config.KeyField: key,
+			}, bson.M{
+				p.config.KeyField:       key,
+				p.config.ValueField:     val,
+				p.config.ExpiredAtField: expiredAt,
+			})
+		}
+		_, er := bulk.Run()
+		return er
+	})
+	return
+}
+
+func (p *mgoProvide) DeleteMulti(keys []string) (err error) {
+	if len(keys) == 0 {
+		return
+	}
+
+	err = p.connect(func(c *mgo.Collection) error {
+		_, er := c.RemoveAll(bson.M{
+			p.config.KeyField: bson.M{"$in": keys},
+		})
+		return er
+	})
+	return
+}
+
 func (p *mgoProvide) Has(key string) (bool, error) {
 	values, err := p.fetchValues(key)
 	if err != nil {
@@ -184,6 +367,41 @@ func (p *mgoProvide) Has(key string) (bool, error) {
 	return ok, nil
 }
 
+func (p *mgoProvide) Keys(prefix string) (keys []string, err error) {
+	err = p.Scan(prefix, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	return
+}
+
+// Scan queries alive keys by regex on KeyField
+func (p *mgoProvide) Scan(prefix string, fn func(key string) error) (err error) {
+	err = p.connect(func(c *mgo.Collection) error {
+		iter := c.Find(bson.M{
+			p.config.KeyField: bson.M{
+				"$regex": "^" + regexp.QuoteMeta(prefix),
+			},
+			p.config.ExpiredAtField: bson.M{
+				"$gt": time.Now(),
+			},
+		}).Select(bson.M{
+			p.config.KeyField: 1,
+		}).Iter()
+
+		var doc map[string]interface{}
+		for iter.Next(&doc) {
+			key, _ := doc[p.config.KeyField].(string)
+			if er := fn(key); er != nil {
+				iter.Close()
+				return er
+			}
+		}
+		return iter.Close()
+	})
+	return
+}
+
 func (p *mgoProvide) Clean() (err error) {
 	err = p.connect(func(c *mgo.Collection) error {
 		_, er := c.RemoveAll(nil)
@@ -1,21 +1,40 @@
 package caches
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/mediocregopher/radix.v2/pool"
 	"github.com/mediocregopher/radix.v2/redis"
 	"github.com/wujiu2020/strip/utils"
 )
 
+const DefaultScanCount = 100
+
+// redisIncrByScript incrs KEYS[1] by ARGV[1] if it exists, returns nil if missed
+const redisIncrByScript = `if redis.call("EXISTS", KEYS[1]) == 1 then return redis.call("INCRBY", KEYS[1], ARGV[1]) else return false end`
+
 type RedisConfig struct {
 	KeyPrefix string
 	Client    *pool.Pool
+	ScanCount int // count of keys per SCAN and UNLINK, DefaultScanCount if 0
 }
 
 type redisProvide struct {
 	RedisConfig
 }
 
-var _ CacheProvider = new(redisProvide)
+var (
+	_ CacheProvider    = new(redisProvide)
+	_ BatchProvider    = new(redisProvide)
+	_ NXProvider       = new(redisProvide)
+	_ CounterProvider  = new(redisProvide)
+	_ CASProvider      = new(redisProvide)
+	_ TTLProvider      = new(redisProvide)
+	_ BatchTTLProvider = new(redisProvide)
+	_ ScanProvider     = new(redisProvide)
+)
 
 func NewRedisProvider(config RedisConfig) (sess CacheProvider, err error) {
 	provider := new(redisProvide)
@@ -142,6 +161,251 @@ func (p *redisProvide) Decr(key string, params ...int) (err error) {
 	return
 }
 
+// IncrBy returns ErrMissedKey if key is missed, the same as other providers,
+// INCRBY of redisIncrByScript only runs on existing key
+func (p *redisProvide) IncrBy(key string, delta int64) (n int64, err error) {
+	key = p.KeyPrefix + key
+
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	resp := conn.Cmd("EVAL", redisIncrByScript, 1, key, delta)
+	if resp.IsType(redis.Nil) {
+		err = ErrMissedKey
+		return
+	}
+	n, err = resp.Int64()
+	return
+}
+
+func (p *redisProvide) DecrBy(key string, delta int64) (int64, error) {
+	return p.IncrBy(key, -delta)
+}
+
+// SetNX maps to SET NX
+func (p *redisProvide) SetNX(key string, val interface{}, params ...int) (ok bool, err error) {
+	key = p.KeyPrefix + key
+	timeout := getTimeoutDur(params...)
+
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	args := []interface{}{key, utils.ToStr(val)}
+	if timeout > 0 {
+		args = append(args, "EX", timeout.Seconds())
+	}
+	resp := conn.Cmd("SET", append(args, "NX")...)
+	if resp.IsType(redis.Nil) {
+		return
+	}
+	err = resp.Err
+	ok = err == nil
+	return
+}
+
+// CAS compares string form of values, and swaps in MULTI of WATCH
+func (p *redisProvide) CAS(key string, old, new interface{}, params ...int) (ok bool, err error) {
+	key = p.KeyPrefix + key
+	timeout := getTimeoutDur(params...)
+
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	if err = conn.Cmd("WATCH", key).Err; err != nil {
+		return
+	}
+	v, err := conn.Cmd("GET", key).Str()
+	if err != nil || v != utils.ToStr(old) {
+		conn.Cmd("UNWATCH")
+		err = p.handleError(err)
+		return
+	}
+
+	args := []interface{}{key, utils.ToStr(new)}
+	if timeout > 0 {
+		args = append(args, "EX", timeout.Seconds())
+	}
+	if err = conn.Cmd("MULTI").Err; err != nil {
+		conn.Cmd("UNWATCH")
+		return
+	}
+	if err = conn.Cmd("SET", args...).Err; err != nil {
+		conn.Cmd("DISCARD")
+		conn.Cmd("UNWATCH")
+		return
+	}
+
+	// EXEC returns nil if the key is changed after WATCH
+	resp := conn.Cmd("EXEC")
+	if resp.IsType(redis.Nil) {
+		return
+	}
+	err = resp.Err
+	ok = err == nil
+	return
+}
+
+func (p *redisProvide) GetMulti(keys []string) (values map[string]utils.StrTo, err error) {
+	values = make(map[string]utils.StrTo, len(keys))
+	if len(keys) == 0 {
+		return
+	}
+
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = p.KeyPrefix + key
+	}
+	resps, err := conn.Cmd("MGET", args...).Array()
+	if err != nil {
+		return
+	}
+	for i, resp := range resps {
+		if resp.IsType(redis.Nil) {
+			continue
+		}
+		v, er := resp.Str()
+		if er != nil {
+			err = er
+			return
+		}
+		values[keys[i]] = utils.StrTo(v)
+	}
+	return
+}
+
+// GetMultiTTL sends GET and PTTL of keys in one pipeline
+func (p *redisProvide) GetMultiTTL(keys []string) (values map[string]TTLValue, err error) {
+	values = make(map[string]TTLValue, len(keys))
+	if len(keys) == 0 {
+		return
+	}
+
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	for _, key := range keys {
+		conn.PipeAppend("GET", p.KeyPrefix+key)
+		conn.PipeAppend("PTTL", p.KeyPrefix+key)
+	}
+	for _, key := range keys {
+		value, ms := conn.PipeResp(), conn.PipeResp()
+		if err != nil {
+			continue
+		}
+		if value.IsType(redis.Nil) {
+			continue
+		}
+		v, er := value.Str()
+		if er != nil {
+			err = er
+			continue
+		}
+		n, er := ms.Int64()
+		switch {
+		case er != nil:
+			err = er
+		case n == -2:
+			// expired after GET
+		case n < 0:
+			values[key] = TTLValue{Value: utils.StrTo(v), TTL: -1}
+		default:
+			values[key] = TTLValue{Value: utils.StrTo(v), TTL: time.Duration(n) * time.Millisecond}
+		}
+	}
+	if err != nil {
+		values = nil
+	}
+	return
+}
+
+// SetMulti sends SET of keys in one pipeline
+func (p *redisProvide) SetMulti(values map[string]interface{}, params ...int) (err error) {
+	if len(values) == 0 {
+		return
+	}
+	timeout := getTimeoutDur(params...)
+
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	for key, val := range values {
+		args := []interface{}{p.KeyPrefix + key, utils.ToStr(val)}
+		if timeout > 0 {
+			args = append(args, "EX", timeout.Seconds())
+		}
+		conn.PipeAppend("SET", args...)
+	}
+	for range values {
+		if resp := conn.PipeResp(); resp.Err != nil && err == nil {
+			err = resp.Err
+		}
+	}
+	return
+}
+
+func (p *redisProvide) DeleteMulti(keys []string) (err error) {
+	if len(keys) == 0 {
+		return
+	}
+
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = p.KeyPrefix + key
+	}
+	err = conn.Cmd("DEL", args...).Err
+	return
+}
+
+// TTL maps to PTTL, which replies -2 if key is missed and -1 if it never expires
+func (p *redisProvide) TTL(key string) (ttl time.Duration, err error) {
+	key = p.KeyPrefix + key
+
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	ms, err := conn.Cmd("PTTL", key).Int64()
+	switch {
+	case err != nil:
+	case ms == -2:
+		err = ErrMissedKey
+	case ms < 0:
+		ttl = -1
+	default:
+		ttl = time.Duration(ms) * time.Millisecond
+	}
+	return
+}
+
 func (p *redisProvide) Has(key string) (exists bool, err error) {
 	key = p.KeyPrefix + key
 
@@ -160,6 +424,7 @@ func (p *redisProvide) Has(key string) (exists bool, err error) {
 	return
 }
 
+// Clean deletes keys under KeyPrefix only, by SCAN and UNLINK in batches
 func (p *redisProvide) Clean() (err error) {
 	conn, err := p.Client.Get()
 	if err != nil {
@@ -167,15 +432,112 @@ func (p *redisProvide) Clean() (err error) {
 	}
 	defer p.Client.Put(conn)
 
-	resp := conn.Cmd("FLUSHDB")
-	err = resp.Err
+	unlink := "UNLINK"
+	err = p.scan(conn, "", func(keys []string) error {
+		args := make([]interface{}, len(keys))
+		for i, key := range keys {
+			args[i] = p.KeyPrefix + key
+		}
+		resp := conn.Cmd(unlink, args...)
+		if unlink == "UNLINK" && isUnknownCommand(resp) {
+			// UNLINK is not supported before redis 4.0
+			unlink = "DEL"
+			resp = conn.Cmd(unlink, args...)
+		}
+		return resp.Err
+	})
 	return
 }
 
+// isUnknownCommand reports whether resp is the error reply of unknown command,
+// not an error of the connection
+func isUnknownCommand(resp *redis.Resp) bool {
+	return resp.IsType(redis.AppErr) && strings.Contains(strings.ToLower(resp.Err.Error()), "unknown command")
+}
+
+func (p *redisProvide) Keys(prefix string) (keys []string, err error) {
+	err = p.Scan(prefix, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	return
+}
+
+// Scan may iterate a key more than once, the same as SCAN of redis
+func (p *redisProvide) Scan(prefix string, fn func(key string) error) (err error) {
+	conn, err := p.Client.Get()
+	if err != nil {
+		return
+	}
+	defer p.Client.Put(conn)
+
+	err = p.scan(conn, prefix, func(keys []string) error {
+		for _, key := range keys {
+			if er := fn(key); er != nil {
+				return er
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// scan calls fn with each non-empty page of keys without KeyPrefix
+func (p *redisProvide) scan(conn *redis.Client, prefix string, fn func(keys []string) error) error {
+	count := p.ScanCount
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+	pattern := globEscape(p.KeyPrefix+prefix) + "*"
+
+	cursor := "0"
+	for {
+		resps, err := conn.Cmd("SCAN", cursor, "MATCH", pattern, "COUNT", count).Array()
+		if err != nil {
+			return err
+		}
+		if len(resps) != 2 {
+			return errors.New("unexpected SCAN reply")
+		}
+		if cursor, err = resps[0].Str(); err != nil {
+			return err
+		}
+		keys, err := resps[1].List()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			for i, key := range keys {
+				keys[i] = key[len(p.KeyPrefix):]
+			}
+			if err = fn(keys); err != nil {
+				return err
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
 func (p *redisProvide) GC() error {
 	return nil
 }
 
+// globEscape escapes special characters of redis glob-style pattern
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
 func (p *redisProvide) handleError(err error) error {
 	if err == redis.ErrRespNil {
 		return ErrMissedKey
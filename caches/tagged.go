@@ -0,0 +1,230 @@
+package caches
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/wujiu2020/strip/utils"
+)
+
+const (
+	DefaultTagPrefix       = "tag:"
+	DefaultNamespacePrefix = "ns:"
+)
+
+var ErrTaggedProvider = errors.New("tagged needs provider")
+
+type TaggedConfig struct {
+	Provider CacheProvider
+	// KeyPrefix is the base of all keys, namespace versions and tag generations in Provider,
+	// set it to KeyPrefix of the provider if Provider doesn't prefix keys itself, eg: memory and mgo
+	KeyPrefix string
+	Namespace string // keys are prefixed by KeyPrefix, namespace and its version, disabled if empty
+	TagPrefix string // key prefix of tag generations after KeyPrefix, DefaultTagPrefix if empty
+}
+
+// Tagged invalidates keys by tags or the whole namespace in O(1),
+// by generations of tags and version of namespace kept in Provider.
+// Values are stored with generations of their tags:
+//
+//	tag1=gen1&tag2=gen2\nvalue
+//
+// and are missed once any generation changed.
+type Tagged struct {
+	TaggedConfig
+}
+
+func NewTagged(config TaggedConfig) (*Tagged, error) {
+	if config.Provider == nil {
+		return nil, ErrTaggedProvider
+	}
+	if config.TagPrefix == "" {
+		config.TagPrefix = DefaultTagPrefix
+	}
+
+	tagged := new(Tagged)
+	tagged.TaggedConfig = config
+	return tagged, nil
+}
+
+// Get returns cached value of key, ErrMissedKey if any tag of it invalidated
+func (t *Tagged) Get(key string) (value utils.StrTo, err error) {
+	key, err = t.key(key)
+	if err != nil {
+		return
+	}
+	v, err := t.Provider.Get(key)
+	if err != nil {
+		return
+	}
+
+	s := v.String()
+	i := strings.IndexByte(s, '\n')
+	if i < 0 {
+		err = ErrMissedKey
+		return
+	}
+	stored, err := url.ParseQuery(s[:i])
+	if err != nil {
+		err = ErrMissedKey
+		return
+	}
+
+	if len(stored) > 0 {
+		tags := make([]string, 0, len(stored))
+		for tag := range stored {
+			tags = append(tags, tag)
+		}
+		var gens map[string]string
+		if gens, err = t.generations(tags); err != nil {
+			return
+		}
+		for tag := range stored {
+			if gens[tag] != stored.Get(tag) {
+				// invalidated, removed lazily
+				t.Provider.Delete(key)
+				err = ErrMissedKey
+				return
+			}
+		}
+	}
+	value = utils.StrTo(s[i+1:])
+	return
+}
+
+// Set caches value without tags
+func (t *Tagged) Set(key string, value interface{}, params ...int) error {
+	ttl := 0
+	if len(params) > 0 {
+		ttl = params[0]
+	}
+	return t.SetWithTags(key, value, ttl)
+}
+
+// SetWithTags caches value with timeout seconds ttl, the value is missed once any of tags invalidated
+func (t *Tagged) SetWithTags(key string, value interface{}, ttl int, tags ...string) error {
+	key, err := t.key(key)
+	if err != nil {
+		return err
+	}
+
+	gens, err := t.generations(tags)
+	if err != nil {
+		return err
+	}
+	header := make(url.Values, len(gens))
+	for tag, gen := range gens {
+		header.Set(tag, gen)
+	}
+	return t.Provider.Set(key, header.Encode()+"\n"+utils.ToStr(value), ttl)
+}
+
+func (t *Tagged) Delete(key string) error {
+	key, err := t.key(key)
+	if err != nil {
+		return err
+	}
+	return t.Provider.Delete(key)
+}
+
+// InvalidateTag invalidates all values of the tags
+func (t *Tagged) InvalidateTag(tags ...string) error {
+	for _, tag := range tags {
+		if err := t.Provider.Set(t.tagKey(tag), newGeneration(), -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateNamespace invalidates all values of the namespace
+func (t *Tagged) InvalidateNamespace() error {
+	if t.Namespace == "" {
+		return nil
+	}
+	return t.Provider.Set(t.KeyPrefix+DefaultNamespacePrefix+t.Namespace, newGeneration(), -1)
+}
+
+// key prefixes key by KeyPrefix, namespace and its version
+func (t *Tagged) key(key string) (string, error) {
+	if t.Namespace == "" {
+		return t.KeyPrefix + key, nil
+	}
+	version, err := t.generation(t.KeyPrefix + DefaultNamespacePrefix + t.Namespace)
+	if err != nil {
+		return "", err
+	}
+	return t.KeyPrefix + t.Namespace + ":" + version + ":" + key, nil
+}
+
+// tagKey is the key of tag generation
+func (t *Tagged) tagKey(tag string) string {
+	return t.KeyPrefix + t.TagPrefix + tag
+}
+
+// generations returns current generations of tags
+func (t *Tagged) generations(tags []string) (map[string]string, error) {
+	gens := make(map[string]string, len(tags))
+	if len(tags) == 0 {
+		return gens, nil
+	}
+
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = t.tagKey(tag)
+	}
+	values, err := Batch(t.Provider).GetMulti(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, tag := range tags {
+		if v, ok := values[keys[i]]; ok {
+			gens[tag] = v.String()
+			continue
+		}
+		if gens[tag], err = t.generation(keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return gens, nil
+}
+
+// generation returns generation of key, initializes it if missed.
+// New generation is unique, so values of evicted generation are not revived.
+func (t *Tagged) generation(key string) (string, error) {
+	v, err := t.Provider.Get(key)
+	if err == nil {
+		return v.String(), nil
+	}
+	if err != ErrMissedKey {
+		return "", err
+	}
+
+	gen := newGeneration()
+	if nx, ok := t.Provider.(NXProvider); ok {
+		set, err := nx.SetNX(key, gen, -1)
+		if err != nil {
+			return "", err
+		}
+		if !set {
+			// initialized by others
+			v, err := t.Provider.Get(key)
+			return v.String(), err
+		}
+		return gen, nil
+	}
+	return gen, t.Provider.Set(key, gen, -1)
+}
+
+var generationSeq uint64
+
+// newGeneration is unique by time, and by sequence in process
+func newGeneration() string {
+	seq := atomic.AddUint64(&generationSeq, 1)
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatUint(seq, 36)
+}
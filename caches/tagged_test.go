@@ -0,0 +1,103 @@
+package caches
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TaggedInvalidateTag(t *testing.T) {
+	provider, _ := NewMemoryProvider(MemoryConfig{})
+	tagged, err := NewTagged(TaggedConfig{Provider: provider})
+	assert.NoError(t, err)
+	// other instance shares the provider
+	other, _ := NewTagged(TaggedConfig{Provider: provider})
+
+	assert.NoError(t, tagged.SetWithTags("user:1", "one", 10, "user", "user:1"))
+	assert.NoError(t, tagged.SetWithTags("user:2", "two", 10, "user", "user:2"))
+	assert.NoError(t, tagged.Set("plain", "plain", 10))
+
+	v, err := other.Get("user:1")
+	assert.NoError(t, err)
+	assert.Equal(t, "one", v.String())
+
+	assert.NoError(t, other.InvalidateTag("user:1"))
+	_, err = tagged.Get("user:1")
+	assert.Equal(t, ErrMissedKey, err)
+	v, err = tagged.Get("user:2")
+	assert.NoError(t, err)
+	assert.Equal(t, "two", v.String())
+
+	assert.NoError(t, tagged.InvalidateTag("user"))
+	_, err = tagged.Get("user:2")
+	assert.Equal(t, ErrMissedKey, err)
+	v, err = tagged.Get("plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", v.String())
+
+	// evicted generation doesn't revive values
+	assert.NoError(t, tagged.SetWithTags("user:3", "three", 10, "user"))
+	assert.NoError(t, provider.Delete(DefaultTagPrefix+"user"))
+	_, err = tagged.Get("user:3")
+	assert.Equal(t, ErrMissedKey, err)
+
+	// value stored without Tagged is missed
+	assert.NoError(t, provider.Set("raw", "raw"))
+	_, err = tagged.Get("raw")
+	assert.Equal(t, ErrMissedKey, err)
+}
+
+func Test_TaggedNamespace(t *testing.T) {
+	provider, _ := NewMemoryProvider(MemoryConfig{})
+	users, _ := NewTagged(TaggedConfig{Provider: provider, Namespace: "users"})
+	posts, _ := NewTagged(TaggedConfig{Provider: provider, Namespace: "posts"})
+
+	assert.NoError(t, users.Set("1", "user"))
+	assert.NoError(t, posts.Set("1", "post"))
+
+	v, err := users.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "user", v.String())
+
+	assert.NoError(t, users.InvalidateNamespace())
+	_, err = users.Get("1")
+	assert.Equal(t, ErrMissedKey, err)
+	v, err = posts.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "post", v.String())
+
+	assert.NoError(t, users.Set("1", "new"))
+	v, err = users.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "new", v.String())
+	assert.NoError(t, users.Delete("1"))
+	_, err = users.Get("1")
+	assert.Equal(t, ErrMissedKey, err)
+}
+
+func Test_TaggedKeyPrefix(t *testing.T) {
+	provider, _ := NewMemoryProvider(MemoryConfig{})
+	a, _ := NewTagged(TaggedConfig{Provider: provider, KeyPrefix: "a:", Namespace: "users"})
+	b, _ := NewTagged(TaggedConfig{Provider: provider, KeyPrefix: "b:", Namespace: "users"})
+
+	assert.NoError(t, a.SetWithTags("1", "a", 10, "user"))
+	assert.NoError(t, b.SetWithTags("1", "b", 10, "user"))
+	has, _ := provider.Has("a:" + DefaultTagPrefix + "user")
+	assert.True(t, has, "tag generation under KeyPrefix")
+	has, _ = provider.Has("a:" + DefaultNamespacePrefix + "users")
+	assert.True(t, has, "namespace version under KeyPrefix")
+
+	// tags and namespaces of other prefix are not shared
+	assert.NoError(t, a.InvalidateTag("user"))
+	_, err := a.Get("1")
+	assert.Equal(t, ErrMissedKey, err)
+	v, err := b.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", v.String())
+
+	assert.NoError(t, a.Set("2", "a"))
+	assert.NoError(t, b.InvalidateNamespace())
+	v, err = a.Get("2")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", v.String())
+}
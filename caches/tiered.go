@@ -0,0 +1,327 @@
+package caches
+
+import (
+	"errors"
+	"time"
+
+	"github.com/wujiu2020/strip/utils"
+)
+
+const (
+	DefaultL1TTL        = 5 // seconds
+	DefaultL1MaxEntries = 10000
+)
+
+var ErrTieredProvider = errors.New("tiered needs L2 provider")
+
+type TieredConfig struct {
+	L1       CacheProvider // in-process cache, NewMemoryProvider of DefaultL1MaxEntries if nil
+	L2       CacheProvider // remote cache shared by instances
+	L1TTL    int           // seconds keeps keys in L1, DefaultL1TTL if 0
+	Notifier Notifier      // drops L1 keys of other instances on writes, L1 is stale up to L1TTL if nil
+}
+
+// Tiered reads through L1 to L2, and writes through both.
+// L1 keeps keys no longer than their remaining timeout in L2 if L2 is BatchTTLProvider, read in the same round-trip.
+// Optional capabilities are of L2, ErrUnsupported is returned if L2 doesn't have them.
+type Tiered struct {
+	TieredConfig
+
+	id     string
+	cancel func()
+}
+
+var (
+	_ CacheProvider   = new(Tiered)
+	_ BatchProvider   = new(Tiered)
+	_ NXProvider      = new(Tiered)
+	_ CounterProvider = new(Tiered)
+	_ CASProvider     = new(Tiered)
+	_ TTLProvider     = new(Tiered)
+)
+
+func NewTiered(config TieredConfig) (*Tiered, error) {
+	if config.L2 == nil {
+		return nil, ErrTieredProvider
+	}
+	if config.L1 == nil {
+		config.L1, _ = NewMemoryProvider(MemoryConfig{MaxEntries: DefaultL1MaxEntries})
+	}
+	if config.L1TTL <= 0 {
+		config.L1TTL = DefaultL1TTL
+	}
+
+	id, err := utils.RandomCreateString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	tiered := new(Tiered)
+	tiered.TieredConfig = config
+	tiered.id = id
+	if config.Notifier != nil {
+		if tiered.cancel, err = config.Notifier.Subscribe(tiered.invalidated); err != nil {
+			return nil, err
+		}
+	}
+	return tiered, nil
+}
+
+// Close stops receiving invalidations of other instances
+func (t *Tiered) Close() error {
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return nil
+}
+
+func (t *Tiered) Get(key string) (value utils.StrTo, err error) {
+	if value, err = t.L1.Get(key); err == nil {
+		return
+	}
+	values, err := t.load([]string{key})
+	if err != nil {
+		return
+	}
+	value, ok := values[key]
+	if !ok {
+		err = ErrMissedKey
+	}
+	return
+}
+
+func (t *Tiered) Set(key string, val interface{}, params ...int) (err error) {
+	if err = t.L2.Set(key, val, params...); err != nil {
+		t.L1.Delete(key)
+		return
+	}
+
+	// L1 expires no later than L2
+	ttl := t.L1TTL
+	if len(params) > 0 && params[0] > 0 && params[0] < ttl {
+		ttl = params[0]
+	}
+	t.L1.Set(key, val, ttl)
+	return t.publish(key)
+}
+
+// Touch drops key from L1 of all instances, which may outlive the new timeout
+func (t *Tiered) Touch(key string, params ...int) (err error) {
+	err = t.L2.Touch(key, params...)
+	t.L1.Delete(key)
+	if err != nil {
+		return
+	}
+	return t.publish(key)
+}
+
+func (t *Tiered) Delete(key string) (err error) {
+	t.L1.Delete(key)
+	if err = t.L2.Delete(key); err != nil && err != ErrMissedKey {
+		return
+	}
+	if er := t.publish(key); er != nil {
+		err = er
+	}
+	return
+}
+
+func (t *Tiered) Incr(key string, params ...int) (err error) {
+	t.L1.Delete(key)
+	if err = t.L2.Incr(key, params...); err != nil {
+		return
+	}
+	return t.publish(key)
+}
+
+func (t *Tiered) Decr(key string, params ...int) (err error) {
+	t.L1.Delete(key)
+	if err = t.L2.Decr(key, params...); err != nil {
+		return
+	}
+	return t.publish(key)
+}
+
+func (t *Tiered) Has(key string) (bool, error) {
+	if ok, err := t.L1.Has(key); err == nil && ok {
+		return true, nil
+	}
+	return t.L2.Has(key)
+}
+
+func (t *Tiered) Clean() (err error) {
+	t.L1.Clean()
+	if err = t.L2.Clean(); err != nil {
+		return
+	}
+	if t.Notifier != nil {
+		err = t.Notifier.Publish(Invalidation{Origin: t.id, All: true})
+	}
+	return
+}
+
+func (t *Tiered) TTL(key string) (time.Duration, error) {
+	ttl, ok := t.L2.(TTLProvider)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return ttl.TTL(key)
+}
+
+func (t *Tiered) SetNX(key string, val interface{}, params ...int) (ok bool, err error) {
+	nx, supported := t.L2.(NXProvider)
+	if !supported {
+		return false, ErrUnsupported
+	}
+	if ok, err = nx.SetNX(key, val, params...); !ok {
+		return
+	}
+	t.L1.Delete(key)
+	return ok, t.publish(key)
+}
+
+func (t *Tiered) IncrBy(key string, delta int64) (n int64, err error) {
+	counter, ok := t.L2.(CounterProvider)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	n, err = counter.IncrBy(key, delta)
+	t.L1.Delete(key)
+	if err != nil {
+		return
+	}
+	return n, t.publish(key)
+}
+
+func (t *Tiered) DecrBy(key string, delta int64) (int64, error) {
+	return t.IncrBy(key, -delta)
+}
+
+func (t *Tiered) CAS(key string, old, new interface{}, params ...int) (ok bool, err error) {
+	cas, supported := t.L2.(CASProvider)
+	if !supported {
+		return false, ErrUnsupported
+	}
+	ok, err = cas.CAS(key, old, new, params...)
+	t.L1.Delete(key)
+	if !ok {
+		return
+	}
+	return ok, t.publish(key)
+}
+
+// GetMulti reads missed keys of L1 from L2 in one operation
+func (t *Tiered) GetMulti(keys []string) (map[string]utils.StrTo, error) {
+	values, err := Batch(t.L1).GetMulti(keys)
+	if err != nil {
+		values = make(map[string]utils.StrTo, len(keys))
+	}
+	missed := make([]string, 0, len(keys)-len(values))
+	for _, key := range keys {
+		if _, ok := values[key]; !ok {
+			missed = append(missed, key)
+		}
+	}
+	if len(missed) == 0 {
+		return values, nil
+	}
+
+	loaded, err := t.load(missed)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range loaded {
+		values[key] = value
+	}
+	return values, nil
+}
+
+func (t *Tiered) SetMulti(values map[string]interface{}, params ...int) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	err := Batch(t.L2).SetMulti(values, params...)
+	Batch(t.L1).DeleteMulti(keys)
+	if err != nil {
+		return err
+	}
+	return t.publish(keys...)
+}
+
+func (t *Tiered) DeleteMulti(keys []string) error {
+	Batch(t.L1).DeleteMulti(keys)
+	if err := Batch(t.L2).DeleteMulti(keys); err != nil {
+		return err
+	}
+	return t.publish(keys...)
+}
+
+func (t *Tiered) GC() (err error) {
+	if err = t.L1.GC(); err != nil {
+		return
+	}
+	return t.L2.GC()
+}
+
+// load reads keys from L2 in one operation and fills L1.
+// Values are kept in L1 no longer than their remaining timeout if L2 is BatchTTLProvider.
+func (t *Tiered) load(keys []string) (map[string]utils.StrTo, error) {
+	batch, ok := t.L2.(BatchTTLProvider)
+	if !ok {
+		values, err := Batch(t.L2).GetMulti(keys)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			t.L1.Set(key, value.String(), t.L1TTL)
+		}
+		return values, nil
+	}
+
+	loaded, err := batch.GetMultiTTL(keys)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]utils.StrTo, len(loaded))
+	for key, v := range loaded {
+		values[key] = v.Value
+		if ttl := t.l1TTL(v.TTL); ttl > 0 {
+			t.L1.Set(key, v.Value.String(), ttl)
+		}
+	}
+	return values, nil
+}
+
+// l1TTL is seconds of L1TTL, or less if remaining timeout in L2 is shorter,
+// 0 if it expires within a second and should not be kept in L1
+func (t *Tiered) l1TTL(remaining time.Duration) int {
+	if remaining < 0 {
+		return t.L1TTL
+	}
+	if seconds := int(remaining / time.Second); seconds < t.L1TTL {
+		return seconds
+	}
+	return t.L1TTL
+}
+
+func (t *Tiered) publish(keys ...string) error {
+	if t.Notifier == nil {
+		return nil
+	}
+	return t.Notifier.Publish(Invalidation{Origin: t.id, Keys: keys})
+}
+
+// invalidated drops L1 keys written by other instances
+func (t *Tiered) invalidated(inv Invalidation) {
+	if inv.Origin == t.id {
+		return
+	}
+	if inv.All {
+		t.L1.Clean()
+		return
+	}
+	for _, key := range inv.Keys {
+		t.L1.Delete(key)
+	}
+}
@@ -0,0 +1,159 @@
+package caches
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TieredNotifier(t *testing.T) {
+	l2, _ := NewMemoryProvider(MemoryConfig{})
+	notifier := NewMemoryNotifier()
+
+	a, err := NewTiered(TieredConfig{L2: l2, Notifier: notifier})
+	assert.NoError(t, err)
+	defer a.Close()
+	b, err := NewTiered(TieredConfig{L2: l2, Notifier: notifier})
+	assert.NoError(t, err)
+	defer b.Close()
+
+	assert.NoError(t, a.Set("key", "v1", 60))
+	has, _ := a.L1.Has("key")
+	assert.True(t, has, "written through L1")
+
+	v, err := b.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", v.String())
+	has, _ = b.L1.Has("key")
+	assert.True(t, has, "read through to L1")
+
+	// L1 of b is dropped by notifier
+	assert.NoError(t, a.Set("key", "v2", 60))
+	v, err = b.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", v.String())
+
+	assert.NoError(t, a.Set("num", 1))
+	b.Get("num")
+	assert.NoError(t, a.Incr("num"))
+	v, err = b.Get("num")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", v.String())
+
+	assert.NoError(t, a.Delete("key"))
+	_, err = b.Get("key")
+	assert.Equal(t, ErrMissedKey, err)
+
+	// capabilities of L2 drop L1 of b too
+	b.Get("num")
+	n, err := a.IncrBy("num", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	v, _ = b.Get("num")
+	assert.Equal(t, "5", v.String())
+
+	swapped, err := a.CAS("num", 5, 6)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+	v, _ = b.Get("num")
+	assert.Equal(t, "6", v.String())
+
+	assert.NoError(t, a.SetMulti(map[string]interface{}{"num": 7, "other": 1}))
+	values, err := b.GetMulti([]string{"num", "other", "missing"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"num": "7", "other": "1"}, map[string]string{
+		"num": values["num"].String(), "other": values["other"].String(),
+	})
+	assert.Len(t, values, 2)
+
+	assert.NoError(t, a.Touch("num", 60))
+	has, _ = b.L1.Has("num")
+	assert.False(t, has, "touched")
+
+	b.Get("num")
+	assert.NoError(t, a.Clean())
+	has, _ = b.L1.Has("num")
+	assert.False(t, has)
+}
+
+func Test_TieredWithoutNotifier(t *testing.T) {
+	l2, _ := NewMemoryProvider(MemoryConfig{})
+	a, _ := NewTiered(TieredConfig{L2: l2})
+	b, _ := NewTiered(TieredConfig{L2: l2})
+
+	assert.NoError(t, a.Set("key", "v1"))
+	b.Get("key")
+	assert.NoError(t, a.Set("key", "v2"))
+
+	// stale up to L1TTL
+	v, err := b.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", v.String())
+	v, err = a.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", v.String())
+
+	_, err = NewTiered(TieredConfig{})
+	assert.Equal(t, ErrTieredProvider, err)
+}
+
+func Test_TieredL1TTL(t *testing.T) {
+	l2, _ := NewMemoryProvider(MemoryConfig{})
+	l1, _ := NewMemoryProvider(MemoryConfig{})
+	tiered, _ := NewTiered(TieredConfig{L1: l1, L2: l2, L1TTL: 60})
+
+	now := time.Now()
+	l1.(*memoryProvide).now = func() time.Time { return now }
+
+	// L1 expires with L2
+	assert.NoError(t, l2.Set("key", "v", 10))
+	tiered.Get("key")
+	ttl, err := l1.(TTLProvider).TTL("key")
+	assert.NoError(t, err)
+	assert.True(t, ttl > 8*time.Second && ttl <= 10*time.Second, "ttl %s", ttl)
+
+	assert.NoError(t, l2.Set("forever", "v", -1))
+	tiered.Get("forever")
+	ttl, _ = l1.(TTLProvider).TTL("forever")
+	assert.Equal(t, 60*time.Second, ttl)
+}
+
+type countingProvider struct {
+	*memoryProvide
+	calls int
+}
+
+func (p *countingProvider) GetMultiTTL(keys []string) (map[string]TTLValue, error) {
+	p.calls++
+	return p.memoryProvide.GetMultiTTL(keys)
+}
+
+func (p *countingProvider) TTL(key string) (time.Duration, error) {
+	p.calls++
+	return p.memoryProvide.TTL(key)
+}
+
+func Test_TieredLoadRoundTrips(t *testing.T) {
+	memory, _ := NewMemoryProvider(MemoryConfig{})
+	l2 := &countingProvider{memoryProvide: memory.(*memoryProvide)}
+	tiered, _ := NewTiered(TieredConfig{L2: l2})
+
+	assert.NoError(t, l2.SetMulti(map[string]interface{}{"a": 1, "b": 2, "c": 3}, 10))
+	values, err := tiered.GetMulti([]string{"a", "b", "c", "d"})
+	assert.NoError(t, err)
+	assert.Len(t, values, 3)
+	assert.Equal(t, 1, l2.calls, "values and timeouts in one operation")
+
+	assert.NoError(t, l2.Set("e", 5, 10))
+	v, err := tiered.Get("e")
+	assert.NoError(t, err)
+	assert.Equal(t, "5", v.String())
+	assert.Equal(t, 2, l2.calls)
+
+	// L1TTL without BatchTTLProvider
+	plain, _ := NewTiered(TieredConfig{L2: struct{ CacheProvider }{memory}, L1TTL: 30})
+	plain.Get("e")
+	ttl, _ := plain.L1.(TTLProvider).TTL("e")
+	assert.True(t, ttl > 29*time.Second, "ttl %s", ttl)
+}
@@ -0,0 +1,33 @@
+package caches
+
+// Typed stores structured values in Provider by Codec,
+// instead of the string form of CacheProvider.Set
+type Typed struct {
+	Provider CacheProvider
+	Codec    Codec // JSONCodec if nil
+}
+
+func (t *Typed) codec() Codec {
+	if t.Codec == nil {
+		return JSONCodec
+	}
+	return t.Codec
+}
+
+// GetInto decodes cached value of key into dst
+func (t *Typed) GetInto(key string, dst interface{}) error {
+	value, err := t.Provider.Get(key)
+	if err != nil {
+		return err
+	}
+	return t.codec().Unmarshal([]byte(value), dst)
+}
+
+// SetValue encodes v and caches it with optional timeout seconds
+func (t *Typed) SetValue(key string, v interface{}, params ...int) error {
+	data, err := t.codec().Marshal(v)
+	if err != nil {
+		return err
+	}
+	return t.Provider.Set(key, data, params...)
+}
@@ -0,0 +1,70 @@
+package caches
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedValue struct {
+	Id        int
+	Name      string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+func Test_TypedCodecs(t *testing.T) {
+	provider, _ := NewMemoryProvider(MemoryConfig{})
+	value := typedValue{
+		Id:        1,
+		Name:      "one",
+		Tags:      []string{"a", "b"},
+		CreatedAt: time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	codecs := map[string]Codec{
+		"json":     JSONCodec,
+		"gob":      GobCodec,
+		"bson":     BsonCodec,
+		"compress": &CompressCodec{Codec: GobCodec},
+	}
+	for name, codec := range codecs {
+		typed := &Typed{Provider: provider, Codec: codec}
+
+		assert.NoError(t, typed.SetValue("struct", value), name)
+		var v typedValue
+		assert.NoError(t, typed.GetInto("struct", &v), name)
+		assert.Equal(t, value.Id, v.Id, name)
+		assert.Equal(t, value.Tags, v.Tags, name)
+		assert.True(t, value.CreatedAt.Equal(v.CreatedAt), name)
+
+		assert.NoError(t, typed.SetValue("int", 10, 10), name)
+		var n int
+		assert.NoError(t, typed.GetInto("int", &n), name)
+		assert.Equal(t, 10, n, name)
+
+		assert.Equal(t, ErrMissedKey, typed.GetInto("missing", &n), name)
+	}
+}
+
+func Test_CompressCodec(t *testing.T) {
+	codec := &CompressCodec{Threshold: 100}
+
+	data, err := codec.Marshal("short")
+	assert.NoError(t, err)
+	assert.Equal(t, `0"short"`, string(data))
+
+	long := strings.Repeat("long", 100)
+	data, err = codec.Marshal(long)
+	assert.NoError(t, err)
+	assert.Equal(t, codecGzip, data[0])
+	assert.True(t, len(data) < len(long))
+
+	var s string
+	assert.NoError(t, codec.Unmarshal(data, &s))
+	assert.Equal(t, long, s)
+
+	assert.Equal(t, ErrCodecData, codec.Unmarshal([]byte("x"), &s))
+}
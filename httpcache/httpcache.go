@@ -0,0 +1,393 @@
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wujiu2020/strip"
+	"github.com/wujiu2020/strip/caches"
+	"github.com/wujiu2020/strip/sessions"
+)
+
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderCacheControl  = "Cache-Control"
+	HeaderContentLength = "Content-Length"
+	HeaderETag          = "ETag"
+	HeaderIfNoneMatch   = "If-None-Match"
+	HeaderSetCookie     = "Set-Cookie"
+	HeaderVary          = "Vary"
+	HeaderXCache        = "X-Cache"
+
+	DefaultTTL         = 60 // seconds
+	DefaultKeyPrefix   = "httpcache:"
+	DefaultMaxBodySize = 1 << 20
+)
+
+// DefaultHeaders are response headers stored with the body
+var DefaultHeaders = []string{
+	"Content-Type",
+	"Content-Encoding",
+	"Content-Language",
+	"Cache-Control",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+	"Vary",
+}
+
+var ErrCacheProvider = errors.New("httpcache needs provider")
+
+type Config struct {
+	Provider      caches.CacheProvider
+	TTL           int      // seconds, DefaultTTL if 0, overridden by max-age of response
+	KeyPrefix     string   // DefaultKeyPrefix if empty
+	Vary          []string // request headers vary the response, eg: Accept-Language
+	Headers       []string // response headers to store, DefaultHeaders if empty
+	MaxBodySize   int      // larger response is not stored, DefaultMaxBodySize if 0
+	BypassCookies []string // requests with any of cookies are not cached, eg: cookie name of sessions
+
+	// Bypass reports whether the request is not cached, eg: of authenticated session.
+	// Requests with Authorization header, or with session or remember cookie of
+	// *sessions.CookieConfig provided to the injector are always bypassed.
+	Bypass func(req *http.Request) bool
+}
+
+// Cache stores full responses of GET and HEAD in Provider
+type Cache struct {
+	Config
+
+	typed *caches.Typed
+}
+
+// entry is the stored response
+type entry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+func New(config Config) (*Cache, error) {
+	if config.Provider == nil {
+		return nil, ErrCacheProvider
+	}
+	if config.TTL == 0 {
+		config.TTL = DefaultTTL
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = DefaultKeyPrefix
+	}
+	if len(config.Headers) == 0 {
+		config.Headers = DefaultHeaders
+	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = DefaultMaxBodySize
+	}
+
+	cache := new(Cache)
+	cache.Config = config
+	cache.typed = &caches.Typed{Provider: config.Provider}
+	return cache, nil
+}
+
+// Filter returns a Handler caches responses for ttl seconds, Config.TTL if not set.
+// It can be used for all requests by Strip.Filter, or per route:
+//
+//	sp.Filter(strip.GenericOutFilter(), cache.Filter())
+//	sp.Routers(strip.Router("/articles", strip.Filter(cache.Filter(300)), strip.Get(...)))
+//
+// ttl of the route overrides the one of all requests.
+// Result of the action is written by the filter as strip.WriteActionOut, so the response is
+// complete before it's stored, GenericOutFilter registered before it finds the response written.
+// Filters replace the ResponseWriter, eg: gzip, must be registered before it, or their output is stored.
+func (c *Cache) Filter(ttl ...int) interface{} {
+	routeTTL := c.TTL
+	if len(ttl) > 0 {
+		routeTTL = ttl[0]
+	}
+
+	return func(ctx strip.Context, rw http.ResponseWriter, req *http.Request, log strip.Logger) {
+		if crw, ok := rw.(*responseWriter); ok && crw.cache == c {
+			// nested in filter of all requests, overrides its ttl
+			crw.ttl = routeTTL
+			return
+		}
+		if (req.Method != http.MethodGet && req.Method != http.MethodHead) || c.bypass(ctx, req) {
+			return
+		}
+
+		directives := parseCacheControl(req.Header.Get(HeaderCacheControl))
+		if _, ok := directives["no-store"]; ok {
+			return
+		}
+
+		key := c.Key(req)
+		_, noCache := directives["no-cache"]
+		if !noCache && directives["max-age"] != "0" {
+			var cached entry
+			err := c.typed.GetInto(key, &cached)
+			if err == nil {
+				cached.write(rw, req)
+				return
+			}
+			if err != caches.ErrMissedKey {
+				log.Warnf("httpcache: get %s %s, %v", req.Method, req.URL, err)
+			}
+		}
+
+		crw := &responseWriter{ResponseWriter: rw.(strip.ResponseWriter), cache: c, ttl: routeTTL}
+		crw.Header().Set(HeaderXCache, "MISS")
+		ctx.ProvideAs(crw, (*http.ResponseWriter)(nil))
+
+		ctx.Next()
+
+		if !crw.Written() {
+			strip.WriteActionOut(ctx, crw, req)
+		}
+		if crw.skip {
+			return
+		}
+		ttl, cacheable := c.ttl(crw, crw.ttl)
+		if cacheable && crw.Header().Get(HeaderETag) == "" {
+			sum := sha1.Sum(crw.buf.Bytes())
+			crw.Header().Set(HeaderETag, `"`+hex.EncodeToString(sum[:])+`"`)
+		}
+		// body of HEAD is not written
+		if cacheable && req.Method == http.MethodGet {
+			c.store(req, key, crw, ttl, log)
+		}
+		crw.flush(req)
+	}
+}
+
+// store saves the buffered response
+func (c *Cache) store(req *http.Request, key string, crw *responseWriter, ttl int, log strip.Logger) {
+	stored := entry{
+		Status: crw.Status(),
+		Header: make(http.Header),
+		Body:   crw.buf.Bytes(),
+	}
+	for _, name := range c.Headers {
+		if values := crw.Header()[http.CanonicalHeaderKey(name)]; len(values) > 0 {
+			stored.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	if stored.Header.Get(HeaderETag) == "" {
+		stored.Header.Set(HeaderETag, crw.Header().Get(HeaderETag))
+	}
+	if err := c.typed.SetValue(key, stored, ttl); err != nil {
+		log.Warnf("httpcache: set %s %s, %v", req.Method, req.URL, err)
+	}
+}
+
+// Key returns cache key of the request from method, path, normalized query and Vary headers
+func (c *Cache) Key(req *http.Request) string {
+	method := req.Method
+	if method == http.MethodHead {
+		// HEAD is served by response of GET
+		method = http.MethodGet
+	}
+
+	h := sha1.New()
+	h.Write([]byte(method + "\n" + req.URL.Path + "\n" + normalizeQuery(req.URL.Query())))
+	for _, name := range c.Vary {
+		h.Write([]byte("\n" + http.CanonicalHeaderKey(name) + ":" + strings.Join(req.Header[http.CanonicalHeaderKey(name)], ",")))
+	}
+	return c.KeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// Purge deletes cached response of the request
+func (c *Cache) Purge(req *http.Request) error {
+	err := c.Provider.Delete(c.Key(req))
+	if err == caches.ErrMissedKey {
+		err = nil
+	}
+	return err
+}
+
+func (c *Cache) bypass(ctx strip.Context, req *http.Request) bool {
+	if req.Header.Get(HeaderAuthorization) != "" {
+		return true
+	}
+	var cookies *sessions.CookieConfig
+	if ctx.Find(&cookies) == nil && cookies != nil {
+		for _, name := range []string{cookies.CookieName, cookies.CookieRememberName} {
+			if _, err := req.Cookie(name); name != "" && err == nil {
+				return true
+			}
+		}
+	}
+	for _, name := range c.BypassCookies {
+		if _, err := req.Cookie(name); err == nil {
+			return true
+		}
+	}
+	return c.Bypass != nil && c.Bypass(req)
+}
+
+// ttl returns timeout seconds to store the response, false if it's not cacheable
+func (c *Cache) ttl(rw *responseWriter, ttl int) (int, bool) {
+	if !cacheableStatus[rw.Status()] || len(rw.Header()[HeaderSetCookie]) > 0 || rw.Header().Get(HeaderVary) == "*" {
+		return 0, false
+	}
+
+	directives := parseCacheControl(rw.Header().Get(HeaderCacheControl))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[d]; ok {
+			return 0, false
+		}
+	}
+	for _, d := range []string{"s-maxage", "max-age"} {
+		if v, ok := directives[d]; ok {
+			age, err := strconv.Atoi(v)
+			if err != nil || age <= 0 {
+				return 0, false
+			}
+			return age, true
+		}
+	}
+	return ttl, ttl > 0
+}
+
+// write serves the entry, or 304 if ETag matched
+func (e *entry) write(rw http.ResponseWriter, req *http.Request) {
+	header := rw.Header()
+	for name, values := range e.Header {
+		header[name] = values
+	}
+	header.Set(HeaderXCache, "HIT")
+
+	if etagMatch(req.Header.Get(HeaderIfNoneMatch), e.Header.Get(HeaderETag)) {
+		header.Del("Content-Type")
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set(HeaderContentLength, strconv.Itoa(len(e.Body)))
+	rw.WriteHeader(e.Status)
+	if req.Method != http.MethodHead {
+		rw.Write(e.Body)
+	}
+}
+
+// responseWriter buffers the body up to MaxBodySize, so ETag of it is sent with the response.
+// Larger or flushed response is streamed and not stored.
+type responseWriter struct {
+	strip.ResponseWriter
+	cache *Cache
+	ttl   int
+	buf   bytes.Buffer
+	skip  bool
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.skip {
+		return w.ResponseWriter.Write(p)
+	}
+	// marks the response as written, header is not sent until the body is written
+	if w.Status() == 0 {
+		w.ResponseWriter.WriteHeader(http.StatusOK)
+	}
+	if w.buf.Len()+len(p) > w.cache.MaxBodySize {
+		w.stream()
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *responseWriter) Flush() {
+	w.stream()
+	w.ResponseWriter.Flush()
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.stream()
+	return w.ResponseWriter.Hijack()
+}
+
+// stream writes the buffered body, and the rest is written directly
+func (w *responseWriter) stream() {
+	if w.skip {
+		return
+	}
+	w.skip = true
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf = bytes.Buffer{}
+}
+
+// flush writes the buffered response, or 304 if ETag matched
+func (w *responseWriter) flush(req *http.Request) {
+	if w.Status() == http.StatusOK && etagMatch(req.Header.Get(HeaderIfNoneMatch), w.Header().Get(HeaderETag)) {
+		w.Header().Del("Content-Type")
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.stream()
+}
+
+// https://tools.ietf.org/html/rfc7231#section-6.1
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// parseCacheControl returns directives with lower case names
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, arg := part, ""
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			name, arg = part[:i], strings.Trim(part[i+1:], `"`)
+		}
+		directives[strings.ToLower(name)] = arg
+	}
+	return directives
+}
+
+// normalizeQuery sorts names and values, so the same query in different order shares the key
+func normalizeQuery(query url.Values) string {
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	return query.Encode()
+}
+
+// etagMatch compares If-None-Match with etag weakly
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip"
+	"github.com/wujiu2020/strip/caches"
+	"github.com/wujiu2020/strip/sessions"
+)
+
+func newTestStrip(t *testing.T, config Config) (*strip.Strip, *int) {
+	if config.Provider == nil {
+		config.Provider, _ = caches.NewMemoryProvider(caches.MemoryConfig{})
+	}
+	cache, err := New(config)
+	assert.NoError(t, err)
+
+	calls := 0
+	sp := strip.New()
+	sp.Filter(strip.GenericOutFilter(), cache.Filter())
+	sp.Routers(
+		strip.Router("/result", strip.Get(func() ([]byte, int) {
+			calls++
+			return []byte("result " + strconv.Itoa(calls)), http.StatusOK
+		})),
+		strip.Router("/articles", strip.Get(func(rw http.ResponseWriter, req *http.Request) {
+			calls++
+			rw.Header().Set("Content-Type", "text/plain")
+			rw.Header().Set("X-Other", "other")
+			rw.Write([]byte("articles " + req.URL.RawQuery + " " + strconv.Itoa(calls)))
+		})),
+		strip.Router("/lang", strip.Get(func(rw http.ResponseWriter, req *http.Request) {
+			calls++
+			rw.Write([]byte(req.Header.Get("Accept-Language")))
+		})),
+		strip.Router("/private", strip.Get(func(rw http.ResponseWriter) {
+			calls++
+			rw.Header().Set(HeaderCacheControl, "private, max-age=60")
+			rw.Write([]byte("private"))
+		})),
+		strip.Router("/error", strip.Get(func(rw http.ResponseWriter) {
+			calls++
+			rw.WriteHeader(http.StatusInternalServerError)
+		})),
+		strip.Router("/short", strip.Filter(cache.Filter(1)), strip.Get(func(rw http.ResponseWriter) {
+			calls++
+			rw.Write([]byte("short"))
+		})),
+	)
+	return sp, &calls
+}
+
+func serve(sp *strip.Strip, method, url string, header ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Add(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	sp.ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_CacheHit(t *testing.T) {
+	sp, calls := newTestStrip(t, Config{})
+
+	rec := serve(sp, "GET", "/articles?b=2&a=1")
+	assert.Equal(t, "articles b=2&a=1 1", rec.Body.String())
+	assert.Equal(t, "MISS", rec.Header().Get(HeaderXCache))
+
+	rec = serve(sp, "GET", "/articles?a=1&b=2")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "articles b=2&a=1 1", rec.Body.String(), "normalized query")
+	assert.Equal(t, "HIT", rec.Header().Get(HeaderXCache))
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "", rec.Header().Get("X-Other"), "not selected")
+	assert.NotEqual(t, "", rec.Header().Get(HeaderETag))
+
+	rec = serve(sp, "HEAD", "/articles?a=1&b=2")
+	assert.Equal(t, "HIT", rec.Header().Get(HeaderXCache))
+	assert.Equal(t, "", rec.Body.String())
+
+	serve(sp, "GET", "/articles?a=2")
+	assert.Equal(t, 2, *calls)
+
+	// refreshed by no-cache
+	rec = serve(sp, "GET", "/articles?a=2", HeaderCacheControl, "no-cache")
+	assert.Equal(t, "articles a=2 3", rec.Body.String())
+	rec = serve(sp, "GET", "/articles?a=2")
+	assert.Equal(t, "articles a=2 3", rec.Body.String())
+}
+
+func Test_CacheNotModified(t *testing.T) {
+	sp, _ := newTestStrip(t, Config{})
+
+	rec := serve(sp, "GET", "/articles")
+	assert.Equal(t, "MISS", rec.Header().Get(HeaderXCache))
+	etag := rec.Header().Get(HeaderETag)
+	assert.NotEqual(t, "", etag, "sent on miss")
+	assert.Equal(t, etag, serve(sp, "GET", "/articles").Header().Get(HeaderETag))
+
+	rec = serve(sp, "GET", "/articles", HeaderIfNoneMatch, `"other", `+etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, "", rec.Body.String())
+	assert.Equal(t, etag, rec.Header().Get(HeaderETag))
+
+	rec = serve(sp, "GET", "/articles", HeaderIfNoneMatch, `"other"`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// validated on miss too
+	sp, calls := newTestStrip(t, Config{})
+	rec = serve(sp, "GET", "/articles", HeaderIfNoneMatch, etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, "MISS", rec.Header().Get(HeaderXCache))
+	assert.Equal(t, "", rec.Body.String())
+	assert.Equal(t, 1, *calls)
+	rec = serve(sp, "GET", "/articles")
+	assert.Equal(t, "HIT", rec.Header().Get(HeaderXCache))
+	assert.Equal(t, "articles  1", rec.Body.String())
+}
+
+func Test_CacheActionResult(t *testing.T) {
+	sp, calls := newTestStrip(t, Config{})
+
+	rec := serve(sp, "GET", "/result")
+	assert.Equal(t, "result 1", rec.Body.String())
+	assert.Equal(t, "MISS", rec.Header().Get(HeaderXCache))
+	assert.NotEqual(t, "", rec.Header().Get(HeaderETag))
+	rec = serve(sp, "GET", "/result")
+	assert.Equal(t, "result 1", rec.Body.String())
+	assert.Equal(t, "HIT", rec.Header().Get(HeaderXCache))
+	assert.Equal(t, 1, *calls)
+
+	// filter of route
+	provider, _ := caches.NewMemoryProvider(caches.MemoryConfig{})
+	cache, _ := New(Config{Provider: provider})
+	sp = strip.New()
+	sp.Filter(strip.GenericOutFilter())
+	sp.Routers(strip.Router("/route", strip.Filter(cache.Filter(60)), strip.Get(func() ([]byte, int) {
+		*calls++
+		return []byte("route"), http.StatusOK
+	})))
+	assert.Equal(t, "route", serve(sp, "GET", "/route").Body.String())
+	rec = serve(sp, "GET", "/route")
+	assert.Equal(t, "route", rec.Body.String())
+	assert.Equal(t, "HIT", rec.Header().Get(HeaderXCache))
+	assert.Equal(t, 2, *calls)
+}
+
+func Test_CacheVary(t *testing.T) {
+	sp, calls := newTestStrip(t, Config{Vary: []string{"accept-language"}})
+
+	assert.Equal(t, "en", serve(sp, "GET", "/lang", "Accept-Language", "en").Body.String())
+	assert.Equal(t, "zh", serve(sp, "GET", "/lang", "Accept-Language", "zh").Body.String())
+	assert.Equal(t, "en", serve(sp, "GET", "/lang", "Accept-Language", "en").Body.String())
+	assert.Equal(t, 2, *calls)
+}
+
+func Test_CacheBypass(t *testing.T) {
+	sp, calls := newTestStrip(t, Config{BypassCookies: []string{"sid"}})
+
+	serve(sp, "GET", "/articles", HeaderAuthorization, "Bearer token")
+	serve(sp, "GET", "/articles", HeaderAuthorization, "Bearer token")
+	serve(sp, "GET", "/articles", "Cookie", "sid=1")
+	serve(sp, "GET", "/articles", "Cookie", "sid=1")
+	serve(sp, "GET", "/articles", HeaderCacheControl, "no-store")
+	serve(sp, "GET", "/articles", HeaderCacheControl, "no-store")
+	assert.Equal(t, 6, *calls)
+
+	// cookies of sessions
+	sp.Provide(&sessions.CookieConfig{CookieName: "SESSION", CookieRememberName: "REMEMBER"})
+	serve(sp, "GET", "/articles", "Cookie", "SESSION=1")
+	serve(sp, "GET", "/articles", "Cookie", "REMEMBER=1")
+	assert.Equal(t, 8, *calls)
+	serve(sp, "GET", "/articles", "Cookie", "other=1")
+	serve(sp, "GET", "/articles", "Cookie", "other=1")
+	assert.Equal(t, 9, *calls)
+
+	serve(sp, "GET", "/private")
+	serve(sp, "GET", "/private")
+	serve(sp, "GET", "/error")
+	serve(sp, "GET", "/error")
+	assert.Equal(t, 13, *calls, "not cacheable")
+
+	// larger body is streamed
+	sp, calls = newTestStrip(t, Config{MaxBodySize: 5})
+	rec := serve(sp, "GET", "/articles")
+	assert.Equal(t, "articles  1", rec.Body.String())
+	assert.Equal(t, "", rec.Header().Get(HeaderETag))
+	serve(sp, "GET", "/articles")
+	assert.Equal(t, 2, *calls)
+}
+
+func Test_CacheRouteTTL(t *testing.T) {
+	sp, calls := newTestStrip(t, Config{})
+
+	serve(sp, "GET", "/short")
+	rec := serve(sp, "GET", "/short")
+	assert.Equal(t, "short", rec.Body.String())
+	assert.Equal(t, 1, *calls)
+
+	time.Sleep(1100 * time.Millisecond)
+	serve(sp, "GET", "/short")
+	assert.Equal(t, 2, *calls)
+}
+
+func Test_NormalizeQuery(t *testing.T) {
+	cache, _ := New(Config{Provider: struct{ caches.CacheProvider }{}})
+	a, _ := http.NewRequest("GET", "/p?x=2&x=1&y=", nil)
+	b, _ := http.NewRequest("HEAD", "/p?y=&x=1&x=2", nil)
+	c, _ := http.NewRequest("GET", "/p?x=1", nil)
+	assert.Equal(t, cache.Key(a), cache.Key(b))
+	assert.NotEqual(t, cache.Key(a), cache.Key(c))
+
+	_, err := New(Config{})
+	assert.Equal(t, ErrCacheProvider, err)
+}
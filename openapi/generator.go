@@ -0,0 +1,262 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/wujiu2020/strip"
+	"github.com/wujiu2020/strip/utils/apires"
+)
+
+const jsonContentType = "application/json; charset=UTF-8"
+
+var (
+	actionResultType = reflect.TypeOf((*strip.ActionResult)(nil)).Elem()
+	handlerType      = reflect.TypeOf((*http.Handler)(nil)).Elem()
+	resBodyTypes     = []reflect.Type{
+		reflect.TypeOf(&apires.ResBody{}),
+		reflect.TypeOf(&apires.ResError{}),
+		reflect.TypeOf(&apires.RawBody{}),
+	}
+)
+
+// RouteDoc annotates a route for document generation
+type RouteDoc struct {
+	Summary     string
+	Description string
+	OperationId string
+	Tags        []string
+	Deprecated  bool
+
+	Params   interface{} // struct bound by params.BindValuesToStruct, documented as query params
+	Body     interface{} // json request body
+	Response interface{} // json response body, override the action return type
+	Status   int         // status of success response, default 200
+}
+
+// Generator builds OpenAPI document from the route tree of strip app.
+// Routes of All and Any methods are skipped, they can't be described by method.
+type Generator struct {
+	Info    Info
+	Servers []Server
+
+	docs    map[string]RouteDoc
+	exclude map[string]bool
+}
+
+func New(info Info) *Generator {
+	return &Generator{
+		Info:    info,
+		docs:    make(map[string]RouteDoc),
+		exclude: make(map[string]bool),
+	}
+}
+
+// Describe annotates route by method and registered path, eg: Describe("GET", "/users/:id", doc)
+func (g *Generator) Describe(method, path string, doc RouteDoc) *Generator {
+	g.docs[docKey(method, path)] = doc
+	return g
+}
+
+// Exclude skips routes of path from document
+func (g *Generator) Exclude(paths ...string) *Generator {
+	for _, p := range paths {
+		g.exclude[p] = true
+	}
+	return g
+}
+
+func (g *Generator) Generate(sp *strip.Strip) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    g.Info,
+		Servers: g.Servers,
+		Paths:   make(map[string]PathItem),
+	}
+
+	s := newSchemas()
+	for _, route := range sp.Routes() {
+		if g.exclude[route.Path] || route.Method == "*" || route.Method == "**" {
+			continue
+		}
+
+		path := convertPath(route.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = g.operation(s, route)
+	}
+
+	if len(s.named) > 0 {
+		doc.Components = &Components{Schemas: s.named}
+	}
+	return doc
+}
+
+// WriteFile exports the indented json document
+func (g *Generator) WriteFile(sp *strip.Strip, filename string) error {
+	b, err := json.MarshalIndent(g.Generate(sp), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0644)
+}
+
+// Handler returns an action serving the json document, which generated at first request
+func (g *Generator) Handler(sp *strip.Strip) interface{} {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(rw http.ResponseWriter, log strip.Logger) {
+		once.Do(func() {
+			body, err = json.Marshal(g.Generate(sp))
+		})
+		if err != nil {
+			log.Error("openapi document marshal failed:", err)
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", jsonContentType)
+		rw.Write(body)
+	}
+}
+
+// Mount serves the document at path of app, the path itself is excluded
+func (g *Generator) Mount(sp *strip.Strip, path string) {
+	g.Exclude(path)
+	sp.Routers(strip.Router(path, strip.Get(g.Handler(sp))))
+}
+
+func (g *Generator) operation(s *schemas, route strip.RouteDesc) *Operation {
+	doc := g.docs[docKey(route.Method, route.Path)]
+
+	op := &Operation{
+		Tags:        doc.Tags,
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		OperationId: doc.OperationId,
+		Deprecated:  doc.Deprecated,
+		Responses:   make(map[string]*Response),
+	}
+
+	// struct action receives route params in order
+	var in []reflect.Type
+	if route.Action != "" && route.Func != nil {
+		for i := 0; i < route.Func.NumIn(); i++ {
+			in = append(in, route.Func.In(i))
+		}
+	}
+
+	pathParams := make(map[string]bool, len(route.Params))
+	for i, name := range route.Params {
+		schema := &Schema{Type: "string"}
+		if i < len(in) {
+			if sc := basicSchema(in[i]); sc != nil {
+				schema = sc
+			}
+		}
+		pathParams[name] = true
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	if doc.Params != nil {
+		for _, param := range s.paramsOf(reflect.TypeOf(doc.Params), "") {
+			if !pathParams[param.Name] {
+				op.Parameters = append(op.Parameters, param)
+			}
+		}
+	}
+
+	if doc.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				"application/json": {Schema: s.schemaOf(reflect.TypeOf(doc.Body))},
+			},
+		}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	res := &Response{Description: http.StatusText(status)}
+	if doc.Response != nil {
+		res.Content = map[string]*MediaType{
+			"application/json": {Schema: s.schemaOf(reflect.TypeOf(doc.Response))},
+		}
+	} else if route.Func != nil {
+		res.Content = responseContent(s, route.Func)
+	}
+	op.Responses[strconv.Itoa(status)] = res
+	return op
+}
+
+// responseContent guess response from action result as GenericOutFilter does
+func responseContent(s *schemas, fn reflect.Type) map[string]*MediaType {
+	outs := make([]reflect.Type, 0, fn.NumOut())
+	for i := 0; i < fn.NumOut(); i++ {
+		outs = append(outs, fn.Out(i))
+	}
+
+	// last int is the status code
+	if len(outs) > 0 && outs[len(outs)-1].Kind() == reflect.Int {
+		outs = outs[:len(outs)-1]
+	}
+	if len(outs) == 0 {
+		return nil
+	}
+
+	typ := outs[len(outs)-1]
+	for _, t := range resBodyTypes {
+		if typ == t {
+			return map[string]*MediaType{"application/json": {Schema: &Schema{}}}
+		}
+	}
+
+	switch {
+	case typ.Implements(actionResultType), typ.Implements(handlerType):
+		return nil
+	case typ.Kind() == reflect.String:
+		return map[string]*MediaType{"text/plain": {Schema: &Schema{Type: "string"}}}
+	case typ == bytesType, typ.Implements(readerType):
+		return map[string]*MediaType{"application/octet-stream": {Schema: &Schema{Type: "string", Format: "binary"}}}
+	}
+	return map[string]*MediaType{"application/json": {Schema: s.schemaOf(typ)}}
+}
+
+// convert route path to OpenAPI template, eg: /users/:id:verb/*:file => /users/{id}:verb/{file}
+func convertPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		switch {
+		case strings.HasPrefix(p, "*:"):
+			parts[i] = "{" + p[2:] + "}"
+		case strings.HasPrefix(p, ":"):
+			name, verb := p[1:], ""
+			if idx := strings.Index(name, ":"); idx != -1 {
+				name, verb = name[:idx], name[idx:]
+			}
+			parts[i] = "{" + name + "}" + verb
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func docKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
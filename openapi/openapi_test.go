@@ -0,0 +1,159 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip"
+	"github.com/wujiu2020/strip/utils/apires"
+)
+
+type Address struct {
+	City string `json:"city"`
+}
+
+type User struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Addresses []Address `json:"addresses"`
+	Friend    *User     `json:"friend,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	secret    string
+}
+
+type UserFilter struct {
+	Id    int               `param:"id"`
+	Page  int               `param:"page"`
+	Tags  []string          `param:"tags"`
+	Extra map[string]string `param:"extra"`
+	Owner struct {
+		Name string `param:"ignored"`
+	} `param:"owner"`
+	Skip    string `param:"-"`
+	Tenant  string `param:"X-Tenant-Id,source=header"`
+	Session string `param:"sid,source=cookie"`
+	Ref     int    `param:"ref,source=route"`
+}
+
+type UserController struct{}
+
+func (c *UserController) Get(id int) (*User, int) {
+	return nil, http.StatusOK
+}
+
+func (c *UserController) Put(id int) *apires.ResBody {
+	return nil
+}
+
+func newApp() *strip.Strip {
+	return strip.New().Routers(
+		strip.Router("/users",
+			strip.Get(func() string { return "" }),
+			strip.Post(func() {}),
+			strip.Router("/:id",
+				strip.Get(&UserController{}),
+				strip.Put(&UserController{}),
+				strip.Any(func() {}),
+			),
+		),
+		strip.Router("/files/*:path", strip.Get(func() []byte { return nil })),
+	)
+}
+
+func Test_Generate(t *testing.T) {
+	sp := newApp()
+
+	gen := New(Info{Title: "test", Version: "1.0"})
+	gen.Describe("GET", "/users", RouteDoc{Summary: "list users", Params: UserFilter{}})
+	gen.Describe("POST", "/users", RouteDoc{Body: User{}, Response: User{}, Status: http.StatusCreated})
+
+	doc := gen.Generate(sp)
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Len(t, doc.Paths, 3)
+
+	list := doc.Paths["/users"]["get"]
+	if assert.NotNil(t, list) {
+		assert.Equal(t, "list users", list.Summary)
+		names := make([]string, 0)
+		for _, p := range list.Parameters {
+			names = append(names, p.In+":"+p.Name)
+		}
+		assert.Equal(t, []string{"query:id", "query:page", "query:tags", "query:extra", "query:owner.Name",
+			"header:X-Tenant-Id", "cookie:sid"}, names)
+		assert.Equal(t, "array", list.Parameters[2].Schema.Type)
+		assert.Equal(t, "deepObject", list.Parameters[3].Style)
+		assert.Equal(t, "text/plain", firstContentType(list.Responses["200"]))
+	}
+
+	create := doc.Paths["/users"]["post"]
+	if assert.NotNil(t, create) {
+		assert.Equal(t, "#/components/schemas/User", create.RequestBody.Content["application/json"].Schema.Ref)
+		assert.NotNil(t, create.Responses["201"])
+	}
+
+	get := doc.Paths["/users/{id}"]["get"]
+	if assert.NotNil(t, get) {
+		assert.Equal(t, "id", get.Parameters[0].Name)
+		assert.Equal(t, "path", get.Parameters[0].In)
+		assert.Equal(t, "integer", get.Parameters[0].Schema.Type)
+		assert.Equal(t, "#/components/schemas/User", get.Responses["200"].Content["application/json"].Schema.Ref)
+	}
+	assert.NotNil(t, doc.Paths["/users/{id}"]["put"])
+	assert.Nil(t, doc.Paths["/users/{id}"]["head"])
+
+	files := doc.Paths["/files/{path}"]["get"]
+	if assert.NotNil(t, files) {
+		assert.Equal(t, "string", files.Parameters[0].Schema.Type)
+		assert.Equal(t, "application/octet-stream", firstContentType(files.Responses["200"]))
+	}
+
+	user := doc.Components.Schemas["User"]
+	if assert.NotNil(t, user) {
+		assert.Len(t, user.Properties, 5)
+		assert.Equal(t, "date-time", user.Properties["created_at"].Format)
+		assert.Equal(t, "#/components/schemas/User", user.Properties["friend"].Ref)
+		assert.Equal(t, "#/components/schemas/Address", user.Properties["addresses"].Items.Ref)
+	}
+}
+
+func Test_MountAndWriteFile(t *testing.T) {
+	sp := newApp()
+	gen := New(Info{Title: "test", Version: "1.0"})
+	gen.Mount(sp, "/openapi.json")
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	sp.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var doc Document
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Len(t, doc.Paths, 3)
+
+	dir, err := ioutil.TempDir("", "openapi")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "openapi.json")
+	assert.NoError(t, gen.WriteFile(sp, filename))
+	b, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, &doc))
+	assert.Equal(t, "test", doc.Info.Title)
+}
+
+func firstContentType(res *Response) string {
+	for typ := range res.Content {
+		return typ
+	}
+	return ""
+}
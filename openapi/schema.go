@@ -0,0 +1,239 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	bytesType     = reflect.TypeOf([]byte(nil))
+	readerType    = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	rawJSONType   = reflect.TypeOf(json.RawMessage(nil))
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+const maxNestLevel = 3
+
+// schemas collects named struct schemas into components
+type schemas struct {
+	named map[string]*Schema
+	names map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		named: make(map[string]*Schema),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns json schema of type, named struct referenced by $ref
+func (s *schemas) schemaOf(typ reflect.Type) *Schema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if schema := basicSchema(typ); schema != nil {
+		return schema
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.schemaOf(typ.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaOf(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return s.structSchema(typ)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.define(typ)}
+	}
+	return &Schema{}
+}
+
+func (s *schemas) define(typ reflect.Type) string {
+	if name, ok := s.names[typ]; ok {
+		return name
+	}
+
+	name := typ.Name()
+	if _, exists := s.named[name]; exists {
+		// same name in different packages
+		parts := strings.Split(typ.PkgPath(), "/")
+		name = parts[len(parts)-1] + "." + name
+	}
+
+	// register before build, break recursive types
+	s.names[typ] = name
+	s.named[name] = nil
+	s.named[name] = s.structSchema(typ)
+	return name
+}
+
+func (s *schemas) structSchema(typ reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.fillFields(schema, typ)
+	return schema
+}
+
+func (s *schemas) fillFields(schema *Schema, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := tag
+		if idx := strings.Index(tag, ","); idx != -1 {
+			name = tag[:idx]
+		}
+
+		ftyp := field.Type
+		for ftyp.Kind() == reflect.Ptr {
+			ftyp = ftyp.Elem()
+		}
+
+		// embedded struct fields are promoted
+		if field.Anonymous && name == "" && ftyp.Kind() == reflect.Struct {
+			s.fillFields(schema, ftyp)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = s.schemaOf(field.Type)
+	}
+}
+
+func basicSchema(typ reflect.Type) *Schema {
+	switch typ {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64"}
+	case bytesType:
+		return &Schema{Type: "string", Format: "byte"}
+	case rawJSONType, interfaceType:
+		return &Schema{}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Interface:
+		return &Schema{}
+	}
+	return nil
+}
+
+// paramsOf returns query parameters of struct bound by params.BindValuesToStruct
+func (s *schemas) paramsOf(typ reflect.Type, prefix string) []*Parameter {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	// stop at recursive types
+	if typ.Kind() != reflect.Struct || strings.Count(prefix, ".") > maxNestLevel {
+		return nil
+	}
+
+	var params []*Parameter
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("param")
+		if tag == "-" {
+			continue
+		}
+
+		ftyp := field.Type
+		for ftyp.Kind() == reflect.Ptr {
+			ftyp = ftyp.Elem()
+		}
+
+		if field.Anonymous {
+			if ftyp.Kind() == reflect.Struct {
+				params = append(params, s.paramsOf(ftyp, prefix)...)
+			}
+			continue
+		}
+
+		name, in := tag, "query"
+		if idx := strings.Index(tag, ","); idx != -1 {
+			name = tag[:idx]
+			in = paramIn(tag[idx+1:])
+		}
+		if in == "" {
+			// route params are documented by path
+			continue
+		}
+		if name == "" || prefix != "" {
+			// fields of nested struct bound by field name
+			name = field.Name
+		}
+		name = prefix + name
+
+		switch {
+		case ftyp.Kind() == reflect.Struct && basicSchema(ftyp) == nil:
+			// nested struct use dot keys, eg: user.Name
+			params = append(params, s.paramsOf(ftyp, name+".")...)
+
+		case ftyp.Kind() == reflect.Map:
+			params = append(params, &Parameter{
+				Name:   name,
+				In:     "query",
+				Style:  "deepObject",
+				Schema: s.schemaOf(ftyp),
+			})
+
+		default:
+			params = append(params, &Parameter{
+				Name:   name,
+				In:     in,
+				Schema: s.schemaOf(ftyp),
+			})
+		}
+	}
+	return params
+}
+
+// paramIn returns location of param by tag source option, empty for route
+func paramIn(opts string) string {
+	for _, opt := range strings.Split(opts, ",") {
+		switch strings.TrimSpace(opt) {
+		case "source=route":
+			return ""
+		case "source=header":
+			return "header"
+		case "source=cookie":
+			return "cookie"
+		}
+	}
+	return "query"
+}
@@ -1,6 +1,7 @@
 package params
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"mime/multipart"
@@ -32,6 +33,54 @@ type Binder struct {
 	//
 	// Note that only exported struct fields may be bound.
 	Bind func(params *Params, name string, typ reflect.Type) reflect.Value
+
+	// BindE is same as Bind but reports the value can not be converted,
+	// the zero value with a *BindError or BindErrors returned on failure.
+	// Binders without BindE never report errors.
+	BindE func(params *Params, name string, typ reflect.Type) (reflect.Value, error)
+}
+
+// BindError describes a param value can not be converted to the type
+type BindError struct {
+	Key   string // key path of param, eg: user.addresses[2].zip
+	Value string
+	Type  reflect.Type
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("param `%s`: can not bind %q as %v: %v", e.Key, e.Value, e.Type, e.Err)
+}
+
+// BindErrors lists every failing key
+type BindErrors []*BindError
+
+func (e BindErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, be := range e {
+		msgs = append(msgs, be.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// collect appends errors returned by BindE
+func (e *BindErrors) collect(err error) {
+	switch v := err.(type) {
+	case nil:
+	case *BindError:
+		*e = append(*e, v)
+	case BindErrors:
+		*e = append(*e, v...)
+	default:
+		*e = append(*e, &BindError{Err: err})
+	}
+}
+
+func (e BindErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
 }
 
 // An adapter for easily making one-key-value binders.
@@ -45,6 +94,32 @@ func ValueBinder(f func(value string, typ reflect.Type) reflect.Value) func(*Par
 	}
 }
 
+// An adapter for easily making one-key-value binders with error.
+func ValueBinderE(f func(value string, typ reflect.Type) (reflect.Value, error)) func(*Params, string, reflect.Type) (reflect.Value, error) {
+	return func(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+		vals, ok := params.Values[name]
+		if !ok || len(vals) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		val, err := f(vals[0], typ)
+		if err != nil {
+			return reflect.Zero(typ), &BindError{Key: name, Value: vals[0], Type: typ, Err: err}
+		}
+		return val, nil
+	}
+}
+
+// makeBinder makes Binder drop the error of BindE for Bind.
+func makeBinder(f func(*Params, string, reflect.Type) (reflect.Value, error)) Binder {
+	return Binder{
+		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
+			val, _ := f(params, name, typ)
+			return val
+		},
+		BindE: f,
+	}
+}
+
 const (
 	DEFAULT_DATE_FORMAT            = "2006-01-02"
 	DEFAULT_DATETIME_FORMAT        = "2006-01-02 15:0"
@@ -61,50 +136,44 @@ var (
 	// automatically attempted when binding a time.Time.
 	TimeFormats = []string{}
 
-	IntBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			intValue, err := strconv.ParseInt(val, 10, 64)
-			if err != nil {
-				return reflect.Zero(typ)
-			}
-			pValue := reflect.New(typ)
-			pValue.Elem().SetInt(intValue)
-			return pValue.Elem()
-		}),
-	}
-
-	UintBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			uintValue, err := strconv.ParseUint(val, 10, 64)
-			if err != nil {
-				return reflect.Zero(typ)
-			}
-			pValue := reflect.New(typ)
-			pValue.Elem().SetUint(uintValue)
-			return pValue.Elem()
-		}),
-	}
-
-	FloatBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			if len(val) == 0 {
-				return reflect.Zero(typ)
-			}
-			floatValue, err := strconv.ParseFloat(val, 64)
-			if err != nil {
-				return reflect.Zero(typ)
-			}
-			pValue := reflect.New(typ)
-			pValue.Elem().SetFloat(floatValue)
-			return pValue.Elem()
-		}),
-	}
+	IntBinder = makeBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		intValue, err := strconv.ParseInt(val, 10, typ.Bits())
+		if err != nil {
+			return reflect.Zero(typ), err
+		}
+		pValue := reflect.New(typ)
+		pValue.Elem().SetInt(intValue)
+		return pValue.Elem(), nil
+	}))
+
+	UintBinder = makeBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		uintValue, err := strconv.ParseUint(val, 10, typ.Bits())
+		if err != nil {
+			return reflect.Zero(typ), err
+		}
+		pValue := reflect.New(typ)
+		pValue.Elem().SetUint(uintValue)
+		return pValue.Elem(), nil
+	}))
+
+	FloatBinder = makeBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		floatValue, err := strconv.ParseFloat(val, typ.Bits())
+		if err != nil {
+			return reflect.Zero(typ), err
+		}
+		pValue := reflect.New(typ)
+		pValue.Elem().SetFloat(floatValue)
+		return pValue.Elem(), nil
+	}))
 
 	StringBinder = Binder{
 		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
@@ -116,63 +185,90 @@ var (
 	// "true" and "false"
 	// "on" and "" (a checkbox)
 	// "1" and "0" (why not)
-	BoolBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			v := strings.TrimSpace(strings.ToLower(val))
-			switch v {
-			case "true", "on", "1":
-				return reflect.ValueOf(true)
-			}
-			// Return false by default.
-			return reflect.ValueOf(false)
-		}),
-	}
+	BoolBinder = makeBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		v := strings.TrimSpace(strings.ToLower(val))
+		switch v {
+		case "true", "on", "1":
+			return reflect.ValueOf(true), nil
+		case "false", "off", "0", "":
+			return reflect.ValueOf(false), nil
+		}
+		// Return false by default.
+		return reflect.ValueOf(false), fmt.Errorf("invalid boolean")
+	}))
 
-	PointerBinder = Binder{
-		Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
-			//return nil if param is unset
-			vals, ok := params.Values[name]
-			if !ok || len(vals) == 0 {
-				return reflect.Zero(typ)
-			}
+	PointerBinder = makeBinder(func(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+		//return nil if param is unset
+		vals, ok := params.Values[name]
+		if !ok || len(vals) == 0 {
+			return reflect.Zero(typ), nil
+		}
 
-			v := Bind(params, name, typ.Elem())
+		v, err := BindE(params, name, typ.Elem())
 
-			p := reflect.New(v.Type()).Elem()
-			p.Set(v)
-			return p.Addr()
-		},
-	}
+		p := reflect.New(v.Type()).Elem()
+		p.Set(v)
+		return p.Addr(), err
+	})
 
-	TimeBinder = Binder{
-		Bind: ValueBinder(func(val string, typ reflect.Type) reflect.Value {
-			for _, f := range TimeFormats {
-				if f == "" {
-					continue
-				}
+	TimeBinder = makeBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		for _, f := range TimeFormats {
+			if f == "" {
+				continue
+			}
 
-				if strings.Contains(f, "07") || strings.Contains(f, "MST") {
-					if r, err := time.Parse(f, val); err == nil {
-						return reflect.ValueOf(r)
-					}
-				} else {
-					if r, err := time.ParseInLocation(f, val, time.Local); err == nil {
-						return reflect.ValueOf(r)
-					}
+			if strings.Contains(f, "07") || strings.Contains(f, "MST") {
+				if r, err := time.Parse(f, val); err == nil {
+					return reflect.ValueOf(r), nil
+				}
+			} else {
+				if r, err := time.ParseInLocation(f, val, time.Local); err == nil {
+					return reflect.ValueOf(r), nil
 				}
 			}
+		}
+
+		if unixInt, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return reflect.ValueOf(time.Unix(unixInt, 0)), nil
+		}
+
+		return reflect.Zero(typ), fmt.Errorf("no time format matched")
+	}))
 
-			if unixInt, err := strconv.ParseInt(val, 10, 64); err == nil {
-				return reflect.ValueOf(time.Unix(unixInt, 0))
+	// DurationBinder accepts time.ParseDuration format, or nanoseconds in integer
+	DurationBinder = makeBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			n, nerr := strconv.ParseInt(val, 10, 64)
+			if nerr != nil {
+				return reflect.Zero(typ), err
 			}
+			d = time.Duration(n)
+		}
+		return reflect.ValueOf(d), nil
+	}))
 
-			return reflect.Zero(typ)
-		}),
-	}
+	// TextBinder binds types implementing encoding.TextUnmarshaler
+	TextBinder = makeBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		if len(val) == 0 {
+			return reflect.Zero(typ), nil
+		}
+		pValue := reflect.New(typ)
+		if err := pValue.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val)); err != nil {
+			return reflect.Zero(typ), err
+		}
+		return pValue.Elem(), nil
+	}))
 
-	MapBinder = Binder{
-		Bind: bindMap,
-	}
+	MapBinder = makeBinder(bindMap)
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
 // Sadly, the binder lookups can not be declared initialized -- that results in
@@ -195,12 +291,13 @@ func init() {
 
 	KindBinders[reflect.String] = StringBinder
 	KindBinders[reflect.Bool] = BoolBinder
-	KindBinders[reflect.Slice] = Binder{bindSlice}
-	KindBinders[reflect.Struct] = Binder{bindStruct}
+	KindBinders[reflect.Slice] = makeBinder(bindSlice)
+	KindBinders[reflect.Struct] = makeBinder(bindStruct)
 	KindBinders[reflect.Ptr] = PointerBinder
 	KindBinders[reflect.Map] = MapBinder
 
 	TypeBinders[reflect.TypeOf(time.Time{})] = TimeBinder
+	TypeBinders[reflect.TypeOf(time.Duration(0))] = DurationBinder
 
 	TimeFormats = append(TimeFormats, DEFAULT_DATE_FORMAT, DEFAULT_DATETIME_FORMAT, DEFAULT_DATETIME_FORMAT_SECOND, time.RFC3339)
 }
@@ -215,11 +312,12 @@ type sliceValue struct {
 // elements, and then sets them to their appropriate location in the slice.
 // If elements are provided without an explicit index, they are added (in
 // unspecified order) to the end of the slice.
-func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
+func bindSlice(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
 	// Collect an array of slice elements with their indexes (and the max index).
 	maxIndex := -1
 	numNoIndex := 0
 	sliceValues := []sliceValue{}
+	var errs BindErrors
 
 	// Factor out the common slice logic (between form values and files).
 	processElement := func(key string, vals []string, files []*multipart.FileHeader) {
@@ -240,9 +338,11 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 			if index > maxIndex {
 				maxIndex = index
 			}
+			value, err := BindE(params, key[:subKeyIndex], typ.Elem())
+			errs.collect(err)
 			sliceValues = append(sliceValues, sliceValue{
 				index: index,
-				value: Bind(params, key[:subKeyIndex], typ.Elem()),
+				value: value,
 			})
 			return
 		}
@@ -251,16 +351,18 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 		numNoIndex += len(vals) + len(files)
 		for _, val := range vals {
 			// Unindexed values can only be direct-bound.
+			value, err := bindValueE(params, key, val, typ.Elem())
+			errs.collect(err)
 			sliceValues = append(sliceValues, sliceValue{
 				index: -1,
-				value: BindValue(val, typ.Elem()),
+				value: value,
 			})
 		}
 
 		for _, fileHeader := range files {
 			sliceValues = append(sliceValues, sliceValue{
 				index: -1,
-				value: BindFile(fileHeader, typ.Elem()),
+				value: Bind(params.sub(nil, map[string][]*multipart.FileHeader{"": {fileHeader}}), "", typ.Elem()),
 			})
 		}
 	}
@@ -288,7 +390,7 @@ func bindSlice(params *Params, name string, typ reflect.Type) reflect.Value {
 		}
 	}
 
-	return resultArray
+	return resultArray, errs.err()
 }
 
 // Break on dots and brackets.
@@ -301,9 +403,10 @@ func nextKey(key string) string {
 	return key[:fieldLen]
 }
 
-func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
+func bindStruct(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
 	result := reflect.New(typ).Elem()
 	fieldValues := make(map[string]reflect.Value)
+	var errs BindErrors
 	for key, _ := range params.Values {
 		if !strings.HasPrefix(key, name+".") {
 			continue
@@ -324,28 +427,40 @@ func bindStruct(params *Params, name string, typ reflect.Type) reflect.Value {
 			if !fieldValue.CanSet() {
 				continue
 			}
-			boundVal := Bind(params, key[:len(name)+1+fieldLen], fieldValue.Type())
+			boundVal, err := BindE(params, key[:len(name)+1+fieldLen], fieldValue.Type())
+			errs.collect(err)
 			fieldValue.Set(boundVal)
 			fieldValues[fieldName] = boundVal
 		}
 	}
 
-	return result
+	return result, errs.err()
 }
 
 // bindMap converts parameters using map syntax into the corresponding map. e.g.:
-//   params["a[5]"]=foo, name="a", typ=map[int]string => map[int]string{5: "foo"}
-func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
+//
+//	params["a[5]"]=foo, name="a", typ=map[int]string => map[int]string{5: "foo"}
+func bindMap(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
 	var (
 		result    = reflect.MakeMap(typ)
 		keyType   = typ.Key()
 		valueType = typ.Elem()
+		errs      BindErrors
 	)
+	setIndex := func(path, key, value string) {
+		k, err := bindValueE(params, path, key, keyType)
+		errs.collect(err)
+		v, err := bindValueE(params, path, value, valueType)
+		errs.collect(err)
+		result.SetMapIndex(k, v)
+	}
 	if v := params.Values.Get(name); v != "" {
 		raw := make(map[string]interface{})
-		_ = json.Unmarshal([]byte(v), &raw)
+		if err := json.Unmarshal([]byte(v), &raw); err != nil {
+			errs.collect(&BindError{Key: name, Value: v, Type: typ, Err: err})
+		}
 		for key, value := range raw {
-			result.SetMapIndex(BindValue(key, keyType), BindValue(fmt.Sprint(value), valueType))
+			setIndex(name+"["+key+"]", key, fmt.Sprint(value))
 		}
 	} else {
 		for paramName, values := range params.Values {
@@ -354,30 +469,68 @@ func bindMap(params *Params, name string, typ reflect.Type) reflect.Value {
 			}
 
 			key := paramName[len(name)+1 : len(paramName)-1]
-			result.SetMapIndex(BindValue(key, keyType), BindValue(values[0], valueType))
+			setIndex(paramName, key, values[0])
 		}
 	}
-	return result
+	return result, errs.err()
 }
 
 // Bind takes the name and type of the desired parameter and constructs it
 // from one or more values from Params.
 // Returns the zero value of the type upon any sort of failure.
 func Bind(params *Params, name string, typ reflect.Type) reflect.Value {
-	if binder, found := binderForType(typ); found {
-		val := binder.Bind(params, name, typ)
-		if val.Type().ConvertibleTo(typ) {
-			val = val.Convert(typ)
-		}
-		return val
+	val, _ := BindE(params, name, typ)
+	return val
+}
+
+// BindE is same as Bind, also returns BindErrors with the key path of
+// every value can not be converted. eg: user.Addresses[2].Zip
+func BindE(params *Params, name string, typ reflect.Type) (reflect.Value, error) {
+	binder, found := params.Binders.binderFor(typ)
+	if !found {
+		return reflect.Zero(typ), nil
+	}
+
+	var (
+		val reflect.Value
+		err error
+	)
+	if binder.BindE != nil {
+		val, err = binder.BindE(params, name, typ)
+	} else {
+		val = binder.Bind(params, name, typ)
 	}
-	return reflect.Zero(typ)
+	if val.Type().ConvertibleTo(typ) {
+		val = val.Convert(typ)
+	}
+	if err != nil {
+		var errs BindErrors
+		errs.collect(err)
+		err = errs
+	}
+	return val, err
 }
 
 func BindValue(val string, typ reflect.Type) reflect.Value {
 	return Bind(&Params{Values: map[string][]string{"": {val}}}, "", typ)
 }
 
+// BindValueE is same as BindValue, returns BindErrors if val can not be converted
+func BindValueE(val string, typ reflect.Type) (reflect.Value, error) {
+	return BindE(&Params{Values: map[string][]string{"": {val}}}, "", typ)
+}
+
+// bindValueE binds single value by binders of params, errors reported with key
+func bindValueE(params *Params, key, val string, typ reflect.Type) (reflect.Value, error) {
+	v, err := BindE(params.sub(map[string][]string{"": {val}}, nil), "", typ)
+	if errs, ok := err.(BindErrors); ok {
+		for _, e := range errs {
+			e.Key = key + e.Key
+		}
+	}
+	return v, err
+}
+
 func BindFile(fileHeader *multipart.FileHeader, typ reflect.Type) reflect.Value {
 	return Bind(&Params{Files: map[string][]*multipart.FileHeader{"": {fileHeader}}}, "", typ)
 }
@@ -387,9 +540,18 @@ func BindValuesToStruct(dest interface{}, values url.Values) {
 	p.BindValuesToStruct(dest)
 }
 
+// BindValuesToStructE is same as BindValuesToStruct, returns BindErrors on failure
+func BindValuesToStructE(dest interface{}, values url.Values) error {
+	p := &Params{Values: values}
+	return p.BindValuesToStructE(dest)
+}
+
 func binderForType(typ reflect.Type) (Binder, bool) {
 	binder, ok := TypeBinders[typ]
 	if !ok {
+		if reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+			return TextBinder, true
+		}
 		binder, ok = KindBinders[typ.Kind()]
 		if !ok {
 			// WARN.Println("no binder for type:", typ)
@@ -0,0 +1,126 @@
+package params
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindErrors(t *testing.T) {
+	type address struct {
+		Zip  int
+		City string
+	}
+	type user struct {
+		Name      string
+		Addresses []address
+		Active    bool
+	}
+	type form struct {
+		Id     int             `param:"id"`
+		Count  uint8           `param:"count"`
+		Scores map[string]int  `param:"scores"`
+		Tags   []int           `param:"tags"`
+		User   user            `param:"user"`
+		Absent int             `param:"absent"`
+		Ptr    *float64        `param:"ptr"`
+		Empty  int             `param:"empty"`
+		Flags  map[string]bool `param:"flags"`
+	}
+
+	values := url.Values{
+		"id":                     {"abc"},
+		"count":                  {"300"},
+		"scores[a]":              {"1"},
+		"scores[b]":              {"x"},
+		"tags[]":                 {"1", "two"},
+		"user.Name":              {"rob"},
+		"user.Addresses[0].Zip":  {"100000"},
+		"user.Addresses[2].Zip":  {"1o0"},
+		"user.Addresses[2].City": {"here"},
+		"user.Active":            {"yes"},
+		"ptr":                    {"1.5.0"},
+		"empty":                  {""},
+		"flags[on]":              {"on"},
+	}
+
+	var f form
+	err := BindValuesToStructE(&f, values)
+	errs, ok := err.(BindErrors)
+	if !assert.True(t, ok, "%v", err) {
+		return
+	}
+
+	failed := make(map[string]string)
+	for _, be := range errs {
+		failed[be.Key] = be.Value
+		assert.NotNil(t, be.Type)
+		assert.Error(t, be.Err)
+	}
+	assert.Equal(t, map[string]string{
+		"id":                    "abc",
+		"count":                 "300",
+		"scores[b]":             "x",
+		"tags[]":                "two",
+		"user.Addresses[2].Zip": "1o0",
+		"user.Active":           "yes",
+		"ptr":                   "1.5.0",
+	}, failed)
+
+	// valid values still bound, same as BindValuesToStruct
+	assert.Equal(t, "rob", f.User.Name)
+	assert.Len(t, f.User.Addresses, 3)
+	assert.Equal(t, 100000, f.User.Addresses[0].Zip)
+	assert.Equal(t, "here", f.User.Addresses[2].City)
+	assert.Equal(t, map[string]int{"a": 1, "b": 0}, f.Scores)
+	assert.Equal(t, []int{1, 0}, f.Tags)
+	assert.Equal(t, map[string]bool{"on": true}, f.Flags)
+	if assert.NotNil(t, f.Ptr) {
+		assert.Equal(t, 0.0, *f.Ptr)
+	}
+
+	var g form
+	BindValuesToStruct(&g, values)
+	assert.Equal(t, f, g)
+
+	assert.NoError(t, BindValuesToStructE(&form{}, url.Values{"id": {"1"}, "user.Active": {"off"}}))
+}
+
+func TestParamsBindE(t *testing.T) {
+	p := &Params{Values: url.Values{"id": {"12x"}, "ok": {"7"}}}
+
+	var id int
+	err := p.BindE(&id, "id")
+	if assert.Error(t, err) {
+		errs := err.(BindErrors)
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "id", errs[0].Key)
+		assert.Equal(t, reflect.TypeOf(0), errs[0].Type)
+	}
+	assert.Equal(t, 0, id)
+
+	assert.NoError(t, p.BindE(&id, "ok"))
+	assert.Equal(t, 7, id)
+
+	// absent is not an error
+	assert.NoError(t, p.BindE(&id, "none"))
+	assert.Equal(t, 0, id)
+}
+
+func TestBindAndValidateTypeError(t *testing.T) {
+	type form struct {
+		Age int `param:"age" validate:"required,min=1"`
+	}
+
+	var f form
+	err := BindAndValidate(&f, url.Values{"age": {"old"}})
+	if assert.Error(t, err) {
+		errs := err.(ValidationErrors)
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "age", errs[0].Field)
+		assert.Equal(t, "type", errs[0].Rule)
+		assert.Equal(t, "int", errs[0].Param)
+	}
+}
@@ -0,0 +1,163 @@
+package params
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+
+	"github.com/wujiu2020/strip/utils/apires"
+)
+
+const (
+	defaultBodyContentType   = "application/json"
+	formContentType          = "application/x-www-form-urlencoded"
+	multipartFormContentType = "multipart/form-data"
+)
+
+var (
+	// DefaultMaxBodySize limits the request body read by BindBody
+	DefaultMaxBodySize int64 = 10 << 20 /* 10 MB */
+
+	ErrBodyTooLarge         = apires.NewResError(http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge, "request body too large")
+	ErrUnsupportedMediaType = apires.NewResError(http.StatusUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported media type")
+	ErrInvalidBody          = apires.NewResError(http.StatusBadRequest, http.StatusBadRequest, "invalid request body")
+
+	errBodyTooLarge = errors.New("request body too large")
+)
+
+// A BodyDecoder decodes request body into dest, body is limited by max body size.
+// Form decoders read p.Form and p.Files instead of body.
+type BodyDecoder func(p *Params, body io.Reader, dest interface{}) error
+
+// BodyDecoders finds decoder by media type of Content-Type,
+// applications can add decoders for other types.
+var BodyDecoders = map[string]BodyDecoder{
+	"application/json":       decodeJSONBody,
+	"application/xml":        decodeXMLBody,
+	"text/xml":               decodeXMLBody,
+	formContentType:          decodeFormBody,
+	multipartFormContentType: decodeFormBody,
+}
+
+// BindBody decodes request body into dest by Content-Type, json if absent,
+// then overlays fields tagged with source, eg: `param:"id,source=route"`.
+//
+// Errors are *apires.ResError: ErrBodyTooLarge, ErrUnsupportedMediaType and
+// ErrInvalidBody, or BindErrors of the overlaid fields, or error of invalid param tags.
+func (p *Params) BindBody(dest interface{}) error {
+	val := reflect.ValueOf(dest)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		panic("need ptr of struct")
+	}
+	if err := checkParamTags(val.Elem().Type()); err != nil {
+		return err
+	}
+
+	var errs BindErrors
+	if p.req != nil && p.req.Body != nil && p.req.Body != http.NoBody {
+		mediaType := defaultBodyContentType
+		if ct := p.req.Header.Get("Content-Type"); ct != "" {
+			var err error
+			if mediaType, _, err = mime.ParseMediaType(ct); err != nil {
+				return ErrUnsupportedMediaType.WithMsgf("unsupported media type: %s", ct)
+			}
+		}
+
+		decode, ok := BodyDecoders[mediaType]
+		if !ok {
+			return ErrUnsupportedMediaType.WithMsgf("unsupported media type: %s", mediaType)
+		}
+
+		body := &limitedReader{r: p.req.Body, n: p.bodySize()}
+		if err := decode(p, body, dest); err != nil {
+			switch e := err.(type) {
+			case BindErrors:
+				// form values can not be parsed
+				errs = e
+			case *apires.ResError:
+				return e
+			default:
+				if err == errBodyTooLarge || body.n < 0 {
+					return ErrBodyTooLarge
+				}
+				return ErrInvalidBody.WithMsgf("decode body err: %v", err)
+			}
+		}
+	}
+
+	b := &structBinding{pointerMap: make(map[uintptr]bool), errs: errs, sourcedOnly: true}
+	p.bindValuesToStruct(val.Elem(), b)
+	return b.errs.err()
+}
+
+func (p *Params) bodySize() int64 {
+	if p.maxBodySize > 0 {
+		return p.maxBodySize
+	}
+	return DefaultMaxBodySize
+}
+
+func decodeJSONBody(p *Params, body io.Reader, dest interface{}) error {
+	err := json.NewDecoder(body).Decode(dest)
+	if err == io.EOF {
+		// empty body
+		return nil
+	}
+	return err
+}
+
+func decodeXMLBody(p *Params, body io.Reader, dest interface{}) error {
+	err := xml.NewDecoder(body).Decode(dest)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// decodeFormBody binds form values, which parsed by ParamsParser usually
+func decodeFormBody(p *Params, body io.Reader, dest interface{}) error {
+	if p.Form == nil {
+		req := *p.req
+		req.Body = readCloser{body, p.req.Body}
+		if err := ParseParams(&Params{req: &req}, p.bodySize()); err != nil {
+			return err
+		}
+		p.Form = req.PostForm
+		if req.MultipartForm != nil {
+			p.Form = req.MultipartForm.Value
+			p.Files = req.MultipartForm.File
+			p.req.MultipartForm = req.MultipartForm
+		}
+	}
+	return p.sub(p.Form, p.Files).BindValuesToStructE(dest)
+}
+
+// limitedReader returns errBodyTooLarge if more than n bytes read
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errBodyTooLarge
+	}
+	if int64(len(b)) > l.n+1 {
+		b = b[:l.n+1]
+	}
+	n, err := l.r.Read(b)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return 0, errBodyTooLarge
+	}
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,190 @@
+package params
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip"
+	"github.com/wujiu2020/strip/utils/apires"
+)
+
+type bodyForm struct {
+	Id    int    `param:"id,source=route" json:"-" xml:"-"`
+	Page  int    `param:"page,source=query" json:"page" xml:"page"`
+	Name  string `param:"name" json:"name" xml:"name"`
+	Count int    `param:"count" json:"count" xml:"count"`
+}
+
+func newBodyParams(t *testing.T, body io.Reader, contentType string) *Params {
+	req := httptest.NewRequest("POST", "/users/7?page=3", body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	p := &Params{req: req, Route: strip.RouteInfo{Values: url.Values{"id": {"7"}}}}
+	assert.NoError(t, ParseParams(p, 0))
+	return p
+}
+
+func TestBindBody(t *testing.T) {
+	want := bodyForm{Id: 7, Page: 3, Name: "rob", Count: 2}
+
+	cases := map[string]string{
+		"application/json; charset=utf-8":   `{"name":"rob","count":2,"page":1}`,
+		"":                                  `{"name":"rob","count":2}`,
+		"application/xml":                   `<bodyForm><name>rob</name><count>2</count></bodyForm>`,
+		"application/x-www-form-urlencoded": `name=rob&count=2&id=1`,
+	}
+	for contentType, body := range cases {
+		var f bodyForm
+		p := newBodyParams(t, strings.NewReader(body), contentType)
+		assert.NoError(t, p.BindBody(&f), contentType)
+		assert.Equal(t, want, f, contentType)
+	}
+
+	// multipart
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	mw.WriteField("name", "rob")
+	mw.WriteField("count", "2")
+	mw.Close()
+
+	var f bodyForm
+	p := newBodyParams(t, buf, mw.FormDataContentType())
+	assert.NoError(t, p.BindBody(&f))
+	assert.Equal(t, want, f)
+
+	// form values can not be parsed
+	f = bodyForm{}
+	p = newBodyParams(t, strings.NewReader("name=rob&count=two"), "application/x-www-form-urlencoded")
+	err := p.BindBody(&f)
+	if assert.IsType(t, BindErrors{}, err) {
+		assert.Equal(t, "count", err.(BindErrors)[0].Key)
+	}
+	assert.Equal(t, 7, f.Id)
+}
+
+func TestBindBodyErrors(t *testing.T) {
+	var f bodyForm
+
+	p := newBodyParams(t, strings.NewReader("name: rob"), "application/yaml")
+	err := p.BindBody(&f)
+	assert.True(t, err.(*apires.ResError).EqualAny(ErrUnsupportedMediaType))
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(*apires.ResError).HttpCode())
+
+	p = newBodyParams(t, strings.NewReader(`{"name":`), "application/json")
+	err = p.BindBody(&f)
+	assert.True(t, err.(*apires.ResError).EqualAny(ErrInvalidBody))
+
+	p = newBodyParams(t, strings.NewReader(`{"name":"`+strings.Repeat("a", 100)+`"}`), "application/json")
+	p.maxBodySize = 64
+	err = p.BindBody(&f)
+	assert.True(t, err.(*apires.ResError).EqualAny(ErrBodyTooLarge))
+
+	// pluggable decoder
+	BodyDecoders["text/plain"] = func(p *Params, body io.Reader, dest interface{}) error {
+		b, err := ioutil.ReadAll(body)
+		dest.(*bodyForm).Name = string(b)
+		return err
+	}
+	defer delete(BodyDecoders, "text/plain")
+
+	f = bodyForm{}
+	p = newBodyParams(t, strings.NewReader("rob"), "text/plain")
+	assert.NoError(t, p.BindBody(&f))
+	assert.Equal(t, bodyForm{Id: 7, Page: 3, Name: "rob"}, f)
+}
+
+func TestSourceTag(t *testing.T) {
+	type form struct {
+		Id   int `param:"id,source=route"`
+		Page int `param:"page"`
+	}
+
+	p := &Params{
+		Query: url.Values{"id": {"1"}, "page": {"2"}},
+		Route: strip.RouteInfo{Values: url.Values{"id": {"7"}}},
+	}
+	p.Values = p.calcValues()
+
+	var f form
+	assert.NoError(t, p.BindValuesToStructE(&f))
+	assert.Equal(t, form{Id: 7, Page: 2}, f)
+}
+
+func TestHeaderCookieSource(t *testing.T) {
+	type form struct {
+		Tenant  int64    `param:"x-tenant-id,source=header"`
+		Langs   []string `param:"Accept-Language,source=header"`
+		Session string   `param:"sid,source=cookie"`
+		Debug   bool     `param:"debug,source=cookie"`
+		Name    string   `param:"name"`
+	}
+
+	req := httptest.NewRequest("GET", "/?name=rob&sid=query", nil)
+	req.Header.Set("X-Tenant-Id", "42")
+	req.Header.Add("Accept-Language", "en")
+	req.Header.Add("Accept-Language", "zh")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "abc"})
+	req.AddCookie(&http.Cookie{Name: "debug", Value: "1"})
+
+	p := &Params{req: req}
+	assert.NoError(t, ParseParams(p, 0))
+
+	var f form
+	assert.NoError(t, p.BindValuesToStructE(&f))
+	assert.Equal(t, form{Tenant: 42, Langs: []string{"en", "zh"}, Session: "abc", Debug: true, Name: "rob"}, f)
+
+	req.Header.Set("X-Tenant-Id", "acme")
+	f = form{}
+	err := p.BindValuesToStructE(&f)
+	if assert.IsType(t, BindErrors{}, err) {
+		assert.Equal(t, "X-Tenant-Id", err.(BindErrors)[0].Key)
+	}
+}
+
+func TestBindBodyKeepsDecoded(t *testing.T) {
+	type form struct {
+		Page int    `param:"page,source=query" json:"page"`
+		Size int    `param:"size,source=query" json:"size" default:"20"`
+		Sort string `param:"sort,source=query" json:"sort" default:"id"`
+	}
+
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"page":2,"size":50}`))
+	p := &Params{req: req}
+	assert.NoError(t, ParseParams(p, 0))
+
+	var f form
+	assert.NoError(t, p.BindBody(&f))
+	assert.Equal(t, form{Page: 2, Size: 50, Sort: "id"}, f, "query lacks the keys")
+}
+
+func TestUnknownSource(t *testing.T) {
+	type form struct {
+		Id int `param:"id,source=rout"`
+	}
+	type embedded struct {
+		form
+		Name string `param:"name"`
+	}
+
+	p := &Params{Values: url.Values{"id": {"1"}, "name": {"rob"}}}
+	for _, dest := range []interface{}{&form{}, &embedded{}} {
+		err := p.BindValuesToStructE(dest)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "unknown param source `rout`")
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+	p = &Params{req: req}
+	assert.Error(t, p.BindBody(&form{}))
+	assert.Error(t, BindAndValidate(&form{}, url.Values{}))
+}
@@ -0,0 +1,185 @@
+package params
+
+import (
+	"encoding"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	durationType      = reflect.TypeOf(time.Duration(0))
+)
+
+// EncodeValues encodes struct to url.Values, the reverse of BindValuesToStruct:
+//
+//	top fields by `param` tag, nested fields by field name, eg: user.Name
+//	slice of values as ul[], slice of structs as ol[0].Name
+//	map as a[k]
+//	time.Time formatted by the first of TimeFormats
+//
+// Zero fields are omitted unless the field has `default` tag.
+// Fields of route, header and cookie source are skipped.
+func EncodeValues(src interface{}) url.Values {
+	val := reflect.ValueOf(src)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return url.Values{}
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		panic("need struct or ptr of struct")
+	}
+
+	values := make(url.Values)
+	encodeStruct(values, val)
+	return values
+}
+
+func encodeStruct(values url.Values, val reflect.Value) {
+	typ := val.Type()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		ftyp := typ.Field(i)
+
+		if ftyp.PkgPath != "" && !ftyp.Anonymous { // skip unexport
+			continue
+		}
+
+		tag := ftyp.Tag.Get("param")
+		if tag == "-" {
+			continue
+		}
+
+		// struct recursion
+		if ftyp.Anonymous {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+			if field.Kind() == reflect.Struct {
+				encodeStruct(values, field)
+			}
+			continue
+		}
+
+		name, source := parseParamTag(tag)
+		if source != "" && source != "query" && source != "form" {
+			continue
+		}
+		if name == "" {
+			name = ftyp.Name
+		}
+
+		_, hasDefault := ftyp.Tag.Lookup("default")
+		encodeValue(values, name, field, !hasDefault)
+	}
+}
+
+func encodeValue(values url.Values, key string, val reflect.Value, omitZero bool) {
+	if s, ok := encodeScalar(val); ok {
+		if !omitZero || !isZero(val) {
+			values.Add(key, s)
+		}
+		return
+	}
+
+	switch val.Kind() {
+	case reflect.Ptr:
+		if !val.IsNil() {
+			// pointer to zero value is not absent
+			encodeValue(values, key, val.Elem(), false)
+		}
+
+	case reflect.Struct:
+		typ := val.Type()
+		for i := 0; i < val.NumField(); i++ {
+			ftyp := typ.Field(i)
+			if ftyp.PkgPath != "" {
+				continue
+			}
+			field := val.Field(i)
+			if ftyp.Anonymous {
+				// promoted fields are bound by field name too
+				if field.Kind() == reflect.Ptr {
+					if field.IsNil() {
+						continue
+					}
+					field = field.Elem()
+				}
+				if field.Kind() == reflect.Struct {
+					encodeValue(values, key, field, omitZero)
+					continue
+				}
+			}
+			encodeValue(values, key+"."+ftyp.Name, field, omitZero)
+		}
+
+	case reflect.Slice:
+		for i := 0; i < val.Len(); i++ {
+			elem := val.Index(i)
+			if s, ok := encodeScalar(elem); ok {
+				values.Add(key+"[]", s)
+				continue
+			}
+			// keep zero fields, the index decides length of slice
+			encodeValue(values, key+"["+strconv.Itoa(i)+"]", elem, false)
+		}
+
+	case reflect.Map:
+		iter := val.MapRange()
+		for iter.Next() {
+			k, ok := encodeScalar(iter.Key())
+			if !ok {
+				continue
+			}
+			if v, ok := encodeScalar(iter.Value()); ok {
+				values.Set(key+"["+k+"]", v)
+			}
+		}
+	}
+}
+
+// encodeScalar formats value bound by one string, same as the binders
+func encodeScalar(val reflect.Value) (string, bool) {
+	switch val.Type() {
+	case timeType:
+		t := val.Interface().(time.Time)
+		if t.IsZero() {
+			return "", true
+		}
+		return t.Format(TimeFormats[0]), true
+	case durationType:
+		return time.Duration(val.Int()).String(), true
+	}
+
+	if reflect.PtrTo(val.Type()).Implements(textUnmarshalerType) && val.Type().Implements(textMarshalerType) {
+		b, err := val.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err == nil
+	}
+
+	switch val.Kind() {
+	case reflect.String:
+		return val.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(val.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(val.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(val.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(val.Float(), 'g', -1, val.Type().Bits()), true
+	case reflect.Ptr:
+		if !val.IsNil() {
+			if s, ok := encodeScalar(val.Elem()); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,149 @@
+package params
+
+import (
+	"math/rand"
+	"net/url"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type encodeAddress struct {
+	Zip  int
+	City string
+}
+
+type encodeUser struct {
+	Name      string
+	Admin     bool
+	Addresses []encodeAddress
+}
+
+type encodeEmbedded struct {
+	Token string `param:"token"`
+}
+
+type encodeForm struct {
+	encodeEmbedded
+	Id      int64             `param:"id"`
+	Count   uint8             `param:"count"`
+	Score   float64           `param:"score"`
+	Ratio   float32           `param:"ratio"`
+	Page    int               `param:"page" default:"1"`
+	Ol      []int             `param:"ol"`
+	Ul      []string          `param:"ul"`
+	Ptr     *int              `param:"ptr"`
+	User    encodeUser        `param:"user"`
+	Attrs   map[string]string `param:"a"`
+	At      time.Time         `param:"at"`
+	Timeout time.Duration     `param:"timeout"`
+	Tenant  string            `param:"X-Tenant,source=header"`
+	Skip    string            `param:"-"`
+}
+
+func randString(r *rand.Rand) string {
+	const chars = "abcXYZ019 -_.,[]=&?%/中文"
+	runes := []rune(chars)
+	b := make([]rune, r.Intn(8))
+	for i := range b {
+		b[i] = runes[r.Intn(len(runes))]
+	}
+	return string(b)
+}
+
+// Generate makes values can round trip, absent slices and maps of top fields are bound as empty
+func (encodeForm) Generate(r *rand.Rand, size int) reflect.Value {
+	f := encodeForm{
+		encodeEmbedded: encodeEmbedded{Token: randString(r)},
+		Id:             r.Int63() - r.Int63(),
+		Count:          uint8(r.Intn(256)),
+		Score:          r.NormFloat64() * 1e6,
+		Ratio:          r.Float32(),
+		Page:           r.Intn(3),
+		Ol:             make([]int, r.Intn(size+1)),
+		Ul:             make([]string, r.Intn(size+1)),
+		Attrs:          make(map[string]string),
+		Timeout:        time.Duration(r.Int63()),
+	}
+	for i := range f.Ol {
+		f.Ol[i] = r.Int()
+	}
+	for i := range f.Ul {
+		f.Ul[i] = randString(r)
+	}
+	if r.Intn(2) == 0 {
+		n := r.Intn(3) - 1
+		f.Ptr = &n
+	}
+	f.User.Name = randString(r)
+	f.User.Admin = r.Intn(2) == 0
+	f.User.Addresses = make([]encodeAddress, r.Intn(size+1))
+	for i := range f.User.Addresses {
+		f.User.Addresses[i] = encodeAddress{Zip: r.Intn(3), City: randString(r)}
+	}
+	if len(f.User.Addresses) == 0 {
+		// nested fields not bound if absent
+		f.User.Addresses = nil
+	}
+	for i := r.Intn(size + 1); i > 0; i-- {
+		f.Attrs[randString(r)+"k"] = randString(r)
+	}
+	if r.Intn(2) == 0 {
+		f.At = time.Date(1970+r.Intn(100), time.Month(1+r.Intn(12)), 1+r.Intn(28), 0, 0, 0, 0, time.Local)
+	}
+	return reflect.ValueOf(f)
+}
+
+func TestEncodeValues(t *testing.T) {
+	n := 0
+	f := encodeForm{
+		encodeEmbedded: encodeEmbedded{Token: "t"},
+		Id:             1,
+		Ol:             []int{1, 2},
+		Ul:             []string{"str", "array"},
+		Ptr:            &n,
+		User:           encodeUser{Name: "rob", Addresses: []encodeAddress{{}, {Zip: 2}}},
+		Attrs:          map[string]string{"k": "v"},
+		At:             time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local),
+		Timeout:        time.Second,
+		Tenant:         "acme",
+		Skip:           "skip",
+	}
+	assert.Equal(t, url.Values{
+		"token":                  {"t"},
+		"id":                     {"1"},
+		"page":                   {"0"},
+		"ol[]":                   {"1", "2"},
+		"ul[]":                   {"str", "array"},
+		"ptr":                    {"0"},
+		"user.Name":              {"rob"},
+		"user.Addresses[0].Zip":  {"0"},
+		"user.Addresses[0].City": {""},
+		"user.Addresses[1].Zip":  {"2"},
+		"user.Addresses[1].City": {""},
+		"a[k]":                   {"v"},
+		"at":                     {"2020-01-02"},
+		"timeout":                {"1s"},
+	}, EncodeValues(&f))
+}
+
+func TestEncodeValuesRoundTrip(t *testing.T) {
+	err := quick.Check(func(f encodeForm) bool {
+		var got encodeForm
+		if err := BindValuesToStructE(&got, EncodeValues(f)); err != nil {
+			t.Log(err)
+			return false
+		}
+		// not encoded
+		f.Tenant, f.Skip = "", ""
+		if !reflect.DeepEqual(f, got) {
+			t.Logf("\nwant %#v\ngot  %#v", f, got)
+			return false
+		}
+		return true
+	}, &quick.Config{MaxCount: 500})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,56 @@
+package listquery
+
+// Op is operator of filter condition
+type Op string
+
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpGt       Op = "gt"
+	OpGte      Op = "gte"
+	OpLt       Op = "lt"
+	OpLte      Op = "lte"
+	OpIn       Op = "in"  // comma separated values
+	OpNin      Op = "nin" // comma separated values
+	OpContains Op = "contains"
+	OpExists   Op = "exists" // true or false
+)
+
+// Node of filter AST, one of *Cond, And and Or
+type Node interface {
+	node()
+}
+
+// Cond compares field with value, Value is []interface{} of OpIn and OpNin,
+// bool of OpExists, string of OpContains
+type Cond struct {
+	Field string
+	Op    Op
+	Value interface{}
+}
+
+// And matches if all nodes matched
+type And []Node
+
+// Or matches if any node matched
+type Or []Node
+
+func (*Cond) node() {}
+func (And) node()   {}
+func (Or) node()    {}
+
+// Walk calls fn of every condition in node
+func Walk(n Node, fn func(c *Cond)) {
+	switch v := n.(type) {
+	case *Cond:
+		fn(v)
+	case And:
+		for _, c := range v {
+			Walk(c, fn)
+		}
+	case Or:
+		for _, c := range v {
+			Walk(c, fn)
+		}
+	}
+}
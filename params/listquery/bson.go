@@ -0,0 +1,74 @@
+package listquery
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+var bsonOps = map[Op]string{
+	OpNe:     "$ne",
+	OpGt:     "$gt",
+	OpGte:    "$gte",
+	OpLt:     "$lt",
+	OpLte:    "$lte",
+	OpIn:     "$in",
+	OpNin:    "$nin",
+	OpExists: "$exists",
+}
+
+// Bson translates filter to bson query of mgo, empty if no filter
+func (q *ListQuery) Bson() bson.M {
+	if q.Filter == nil {
+		return bson.M{}
+	}
+	return ToBson(q.Filter)
+}
+
+// BsonSort returns fields of mgo Query.Sort, eg: ["-created", "name"]
+func (q *ListQuery) BsonSort() []string {
+	fields := make([]string, 0, len(q.Sorts))
+	for _, s := range q.Sorts {
+		if s.Desc {
+			fields = append(fields, "-"+s.Field)
+		} else {
+			fields = append(fields, s.Field)
+		}
+	}
+	return fields
+}
+
+// ToBson translates filter node to bson query
+func ToBson(n Node) bson.M {
+	switch v := n.(type) {
+	case *Cond:
+		return bson.M{v.Field: condBson(v)}
+	case And:
+		return bson.M{"$and": nodesBson(v)}
+	case Or:
+		return bson.M{"$or": nodesBson(v)}
+	}
+	panic(fmt.Sprintf("unknown filter node %T", n))
+}
+
+func nodesBson(nodes []Node) []bson.M {
+	list := make([]bson.M, 0, len(nodes))
+	for _, n := range nodes {
+		list = append(list, ToBson(n))
+	}
+	return list
+}
+
+func condBson(c *Cond) interface{} {
+	switch c.Op {
+	case OpEq:
+		return c.Value
+	case OpContains:
+		return bson.RegEx{Pattern: regexp.QuoteMeta(fmt.Sprint(c.Value)), Options: "i"}
+	}
+	if op, ok := bsonOps[c.Op]; ok {
+		return bson.M{op: c.Value}
+	}
+	panic(fmt.Sprintf("unknown filter operator `%s`", c.Op))
+}
@@ -0,0 +1,290 @@
+// Package listquery parses pagination, sorting and filters of list endpoints:
+//
+//	GET /users?page=2&per_page=50&sort=-created,name&filter[status]=active&filter[age][gte]=18
+//
+// Fields are checked against the allow-list of Spec, filters are parsed into
+// a neutral AST which can be translated to bson filter of mgo.
+package listquery
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wujiu2020/strip/params"
+)
+
+const (
+	DefaultPerPage    = 20
+	DefaultMaxPerPage = 100
+
+	HeaderTotalCount = "X-Total-Count"
+)
+
+// Spec declares the allow-list of one endpoint
+type Spec struct {
+	Filters     map[string][]Op         // ops allowed of filter fields, eg: {"status": {OpEq, OpIn}}
+	Types       map[string]reflect.Type // value types of filter fields converted by params binders, string by default
+	Sorts       []string                // fields allowed to sort
+	Columns     map[string]string       // names in store of fields, same as query name by default
+	DefaultSort string                  // eg: -created
+	PerPage     int                     // DefaultPerPage if 0
+	MaxPerPage  int                     // DefaultMaxPerPage if 0, larger per_page is cut
+}
+
+type Sort struct {
+	Field string // name in store
+	Desc  bool
+}
+
+// ListQuery is parsed from page, per_page, sort and filter params
+type ListQuery struct {
+	Page    int
+	PerPage int
+	Sorts   []Sort
+	Filter  Node // nil if no filter
+}
+
+// Parse parses list query from params
+func Parse(p *params.Params, spec *Spec) (*ListQuery, error) {
+	return ParseValues(p.Values, spec)
+}
+
+// ParseValues parses list query from values, errors are params.ValidationErrors
+func ParseValues(values url.Values, spec *Spec) (*ListQuery, error) {
+	var errs params.ValidationErrors
+	fail := func(field, rule, param, format string, args ...interface{}) {
+		errs = append(errs, &params.FieldError{Field: field, Rule: rule, Param: param, Message: fmt.Sprintf(format, args...)})
+	}
+
+	q := &ListQuery{Page: 1, PerPage: spec.PerPage}
+	if q.PerPage <= 0 {
+		q.PerPage = DefaultPerPage
+	}
+	maxPerPage := spec.MaxPerPage
+	if maxPerPage <= 0 {
+		maxPerPage = DefaultMaxPerPage
+	}
+
+	if v := values.Get("page"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 1 {
+			fail("page", "min", "1", "must be a positive integer")
+		} else {
+			q.Page = n
+		}
+	}
+	if v := values.Get("per_page"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 1 {
+			fail("per_page", "min", "1", "must be a positive integer")
+		} else {
+			q.PerPage = n
+		}
+	}
+	if q.PerPage > maxPerPage {
+		q.PerPage = maxPerPage
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	for _, s := range strings.Split(sortParam, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		desc := strings.HasPrefix(s, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+		if !contains(spec.Sorts, name) {
+			fail("sort", "oneof", strings.Join(spec.Sorts, " "), "can not sort by `%s`", name)
+			continue
+		}
+		q.Sorts = append(q.Sorts, Sort{Field: spec.column(name), Desc: desc})
+	}
+
+	// sorted for stable filter
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var conds And
+	for _, key := range keys {
+		name, op, ok := parseFilterKey(key)
+		if !ok {
+			fail(key, "format", "", "must be filter[field] or filter[field][op]")
+			continue
+		}
+		ops, ok := spec.Filters[name]
+		if !ok {
+			fail(key, "oneof", "", "can not filter by `%s`", name)
+			continue
+		}
+		if !containsOp(ops, op) {
+			fail(key, "oneof", joinOps(ops), "operator `%s` not allowed", op)
+			continue
+		}
+
+		value, err := spec.convert(name, op, values.Get(key))
+		if err != nil {
+			fail(key, "type", "", "%v", err)
+			continue
+		}
+		conds = append(conds, &Cond{Field: spec.column(name), Op: op, Value: value})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	switch len(conds) {
+	case 0:
+	case 1:
+		q.Filter = conds[0]
+	default:
+		q.Filter = conds
+	}
+	return q, nil
+}
+
+// Skip returns count of items before the page
+func (q *ListQuery) Skip() int {
+	return (q.Page - 1) * q.PerPage
+}
+
+func (q *ListQuery) Limit() int {
+	return q.PerPage
+}
+
+// SetHeaders sets X-Total-Count and Link of first, prev, next and last pages,
+// links are built from u by replacing the page param, eg: params.RealURL()
+func (q *ListQuery) SetHeaders(h http.Header, u *url.URL, total int) {
+	h.Set(HeaderTotalCount, strconv.Itoa(total))
+
+	last := int(math.Ceil(float64(total) / float64(q.PerPage)))
+	if last < 1 {
+		last = 1
+	}
+
+	link := func(page int, rel string) string {
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(q.PerPage))
+		d := *u
+		d.RawQuery = query.Encode()
+		return fmt.Sprintf(`<%s>; rel="%s"`, d.String(), rel)
+	}
+
+	links := []string{link(1, "first")}
+	if q.Page > 1 {
+		prev := q.Page - 1
+		if prev > last {
+			prev = last
+		}
+		links = append(links, link(prev, "prev"))
+	}
+	if q.Page < last {
+		links = append(links, link(q.Page+1, "next"))
+	}
+	links = append(links, link(last, "last"))
+	h.Set("Link", strings.Join(links, ", "))
+}
+
+func (s *Spec) column(name string) string {
+	if c, ok := s.Columns[name]; ok {
+		return c
+	}
+	return name
+}
+
+// convert converts filter value by type of field, list for in and nin
+func (s *Spec) convert(name string, op Op, value string) (interface{}, error) {
+	typ := s.Types[name]
+	if typ == nil {
+		typ = reflect.TypeOf("")
+	}
+
+	convert := func(v string) (interface{}, error) {
+		val, err := params.BindValueE(v, typ)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", v)
+		}
+		return val.Interface(), nil
+	}
+
+	switch op {
+	case OpIn, OpNin:
+		var list []interface{}
+		for _, v := range strings.Split(value, ",") {
+			val, err := convert(v)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		return list, nil
+	case OpExists:
+		return strconv.ParseBool(value)
+	case OpContains:
+		return value, nil
+	}
+	return convert(value)
+}
+
+// parseFilterKey parses filter[field] and filter[field][op]
+func parseFilterKey(key string) (name string, op Op, ok bool) {
+	rest := strings.TrimPrefix(key, "filter[")
+	idx := strings.Index(rest, "]")
+	if idx < 1 {
+		return
+	}
+	name, rest = rest[:idx], rest[idx+1:]
+	if rest == "" {
+		return name, OpEq, true
+	}
+	if !strings.HasPrefix(rest, "[") || !strings.HasSuffix(rest, "]") {
+		return
+	}
+	op = Op(rest[1 : len(rest)-1])
+	return name, op, op != ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOp(ops []Op, op Op) bool {
+	if len(ops) == 0 {
+		return op == OpEq
+	}
+	for _, v := range ops {
+		if v == op {
+			return true
+		}
+	}
+	return false
+}
+
+func joinOps(ops []Op) string {
+	if len(ops) == 0 {
+		return string(OpEq)
+	}
+	s := make([]string, 0, len(ops))
+	for _, op := range ops {
+		s = append(s, string(op))
+	}
+	return strings.Join(s, " ")
+}
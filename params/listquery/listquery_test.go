@@ -0,0 +1,122 @@
+package listquery
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip/params"
+)
+
+var userSpec = &Spec{
+	Filters: map[string][]Op{
+		"status":  {OpEq, OpIn},
+		"age":     {OpGte, OpLt},
+		"name":    {OpContains},
+		"created": {OpGt},
+	},
+	Types: map[string]reflect.Type{
+		"age":     reflect.TypeOf(0),
+		"created": reflect.TypeOf(time.Time{}),
+	},
+	Sorts:       []string{"created", "name"},
+	Columns:     map[string]string{"created": "created_at"},
+	DefaultSort: "-created",
+	MaxPerPage:  50,
+}
+
+func TestParseValues(t *testing.T) {
+	values, _ := url.ParseQuery("page=3&per_page=80&sort=name,-created&filter[status][in]=active,locked&filter[age][gte]=18&filter[name][contains]=a.b")
+	q, err := ParseValues(values, userSpec)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 3, q.Page)
+	assert.Equal(t, 50, q.PerPage)
+	assert.Equal(t, 100, q.Skip())
+	assert.Equal(t, []Sort{{Field: "name"}, {Field: "created_at", Desc: true}}, q.Sorts)
+	assert.Equal(t, And{
+		&Cond{Field: "age", Op: OpGte, Value: 18},
+		&Cond{Field: "name", Op: OpContains, Value: "a.b"},
+		&Cond{Field: "status", Op: OpIn, Value: []interface{}{"active", "locked"}},
+	}, q.Filter)
+
+	assert.Equal(t, bson.M{"$and": []bson.M{
+		{"age": bson.M{"$gte": 18}},
+		{"name": bson.RegEx{Pattern: `a\.b`, Options: "i"}},
+		{"status": bson.M{"$in": []interface{}{"active", "locked"}}},
+	}}, q.Bson())
+	assert.Equal(t, []string{"name", "-created_at"}, q.BsonSort())
+
+	// defaults
+	q, err = ParseValues(url.Values{"filter[status]": {"active"}}, userSpec)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, q.Page)
+		assert.Equal(t, DefaultPerPage, q.PerPage)
+		assert.Equal(t, []string{"-created_at"}, q.BsonSort())
+		assert.Equal(t, bson.M{"status": "active"}, q.Bson())
+	}
+
+	p := &params.Params{Values: url.Values{}}
+	q, err = Parse(p, &Spec{})
+	if assert.NoError(t, err) {
+		assert.Nil(t, q.Filter)
+		assert.Equal(t, bson.M{}, q.Bson())
+	}
+}
+
+func TestParseValuesErrors(t *testing.T) {
+	values, _ := url.ParseQuery("page=0&sort=password&filter[secret]=1&filter[age][gt]=1&filter[age][gte]=old&filter[x=1")
+	_, err := ParseValues(values, userSpec)
+	errs, ok := err.(params.ValidationErrors)
+	if !assert.True(t, ok) {
+		return
+	}
+	failed := make(map[string]string)
+	for _, fe := range errs {
+		failed[fe.Field] = fe.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"page":             "min",
+		"sort":             "oneof",
+		"filter[secret]":   "oneof",
+		"filter[age][gt]":  "oneof",
+		"filter[age][gte]": "type",
+		"filter[x":         "format",
+	}, failed)
+	assert.Equal(t, http.StatusUnprocessableEntity, errs.ResError().HttpCode())
+}
+
+func TestSetHeaders(t *testing.T) {
+	u, _ := url.Parse("https://example.com/users?sort=name&page=2")
+	q := &ListQuery{Page: 2, PerPage: 10}
+
+	h := make(http.Header)
+	q.SetHeaders(h, u, 35)
+	assert.Equal(t, "35", h.Get(HeaderTotalCount))
+	assert.Equal(t, `<https://example.com/users?page=1&per_page=10&sort=name>; rel="first", `+
+		`<https://example.com/users?page=1&per_page=10&sort=name>; rel="prev", `+
+		`<https://example.com/users?page=3&per_page=10&sort=name>; rel="next", `+
+		`<https://example.com/users?page=4&per_page=10&sort=name>; rel="last"`, h.Get("Link"))
+
+	h = make(http.Header)
+	(&ListQuery{Page: 1, PerPage: 10}).SetHeaders(h, u, 0)
+	assert.Equal(t, `<https://example.com/users?page=1&per_page=10&sort=name>; rel="first", `+
+		`<https://example.com/users?page=1&per_page=10&sort=name>; rel="last"`, h.Get("Link"))
+}
+
+func TestToBsonOr(t *testing.T) {
+	n := Or{&Cond{Field: "a", Op: OpExists, Value: true}, And{&Cond{Field: "b", Op: OpNe, Value: 1}}}
+	assert.Equal(t, bson.M{"$or": []bson.M{
+		{"a": bson.M{"$exists": true}},
+		{"$and": []bson.M{{"b": bson.M{"$ne": 1}}}},
+	}}, ToBson(n))
+
+	var fields []string
+	Walk(n, func(c *Cond) { fields = append(fields, c.Field) })
+	assert.Equal(t, []string{"a", "b"}, fields)
+}
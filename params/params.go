@@ -11,6 +11,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/wujiu2020/strip"
 )
@@ -37,8 +38,13 @@ type Params struct {
 	Files    map[string][]*multipart.FileHeader // Files uploaded in a multipart form
 	tmpFiles []*os.File                         // Temp files used during the request.
 
-	req *http.Request
-	log strip.Logger
+	Binders *BinderRegistry // Binders of the app, globals only if nil
+
+	req         *http.Request
+	log         strip.Logger
+	maxBodySize int64 // limit of BindBody, DefaultMaxBodySize if 0
+
+	streamMultipart bool // leave multipart body for StreamUpload
 }
 
 func ParseParams(params *Params, maxMemory int64) error {
@@ -64,6 +70,9 @@ func ParseParams(params *Params, maxMemory int64) error {
 		}
 
 	case "multipart/form-data":
+		if params.streamMultipart {
+			break
+		}
 		// Multipart form.
 		if maxMemory <= 0 {
 			maxMemory = DefaultMaxMemory
@@ -95,6 +104,22 @@ func (p *Params) Bind(dest interface{}, name string) {
 	value.Set(Bind(p, name, value.Type()))
 }
 
+// BindE is same as Bind, returns BindErrors if the value can not be parsed.
+// Absent parameters are not errors, "dest" is set to the zero value.
+func (p *Params) BindE(dest interface{}, name string) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr {
+		panic("non-pointer passed to Bind: " + name)
+	}
+	value = value.Elem()
+	if !value.CanSet() {
+		panic("non-settable variable passed to Bind: " + name)
+	}
+	val, err := BindE(p, name, value.Type())
+	value.Set(val)
+	return err
+}
+
 func (p *Params) BindJsonBody(dest interface{}, userNumber ...bool) (err error) {
 	body, err := ioutil.ReadAll(p.req.Body)
 	if err != nil {
@@ -123,16 +148,34 @@ func (p *Params) BindJsonBody(dest interface{}, userNumber ...bool) (err error)
 // can use url.Query / url.Values, parse to json
 // can use url.Query / url.Values, parse to json
 func (p *Params) BindValuesToStruct(dest interface{}) {
+	p.BindValuesToStructE(dest)
+}
+
+// BindValuesToStructE is same as BindValuesToStruct, fields can be parsed are
+// still bound, returns BindErrors of the others, or error of invalid param tags.
+func (p *Params) BindValuesToStructE(dest interface{}) error {
 	pointerMap := make(map[uintptr]bool)
 	val := reflect.ValueOf(dest)
 	elm := reflect.Indirect(val)
 	if val.Kind() != reflect.Ptr && elm.Kind() != reflect.Struct {
 		panic("need ptr of struct")
 	}
-	p.bindValuesToStruct(elm, pointerMap)
+	if err := checkParamTags(elm.Type()); err != nil {
+		return err
+	}
+	b := &structBinding{pointerMap: pointerMap}
+	p.bindValuesToStruct(elm, b)
+	return b.errs.err()
+}
+
+// structBinding keeps the state of binding one struct
+type structBinding struct {
+	pointerMap  map[uintptr]bool
+	errs        BindErrors
+	sourcedOnly bool // only bind fields with source option, eg: overlay on decoded body
 }
 
-func (p *Params) bindValuesToStruct(elm reflect.Value, pointerMap map[uintptr]bool) (exits bool) {
+func (p *Params) bindValuesToStruct(elm reflect.Value, b *structBinding) (exits bool) {
 	typ := elm.Type()
 
 	for i := 0; i < elm.NumField(); i++ {
@@ -148,22 +191,18 @@ func (p *Params) bindValuesToStruct(elm reflect.Value, pointerMap map[uintptr]bo
 			continue
 		}
 
-		name := tag
+		name, source := parseParamTag(tag)
 		if name == "" {
 			name = ftyp.Name
 		}
 
-		if idx := strings.Index(tag, ","); idx != -1 {
-			name = tag[:idx]
-		}
-
 		// struct recursion
 		if ftyp.Anonymous {
 			var inf reflect.Value
 
 			if field.Kind() == reflect.Ptr {
 				pointer := field.Pointer()
-				if pointerMap[pointer] {
+				if b.pointerMap[pointer] {
 					continue
 				}
 
@@ -181,26 +220,48 @@ func (p *Params) bindValuesToStruct(elm reflect.Value, pointerMap map[uintptr]bo
 
 				if pointer > 0 {
 					// save pointer
-					pointerMap[pointer] = true
+					b.pointerMap[pointer] = true
 				}
-				if ok := p.bindValuesToStruct(inf, pointerMap); ok {
+				if ok := p.bindValuesToStruct(inf, b); ok {
 					field.Set(newField)
 				}
 
 			} else if field.Kind() == reflect.Struct {
 				inf = field
-				p.bindValuesToStruct(inf, pointerMap)
+				p.bindValuesToStruct(inf, b)
 			} else {
 				continue
 			}
 
 		} else {
+			if b.sourcedOnly && source == "" {
+				continue
+			}
+
+			src := p
+			if source != "" {
+				if source == "header" {
+					name = http.CanonicalHeaderKey(name)
+				}
+				src = p.sub(p.sourceValues(source), p.Files)
+			}
 			if !exits {
-				if vals, ok := p.Values[name]; ok && len(vals) > 0 {
+				if vals, ok := src.Values[name]; ok && len(vals) > 0 {
 					exits = true
 				}
 			}
-			paramValue := Bind(p, name, field.Type())
+			if !src.hasParam(name) {
+				def, hasDefault := ftyp.Tag.Lookup("default")
+				if b.sourcedOnly && (!hasDefault || !isZero(field)) {
+					// keep the value decoded from body
+					continue
+				}
+				if hasDefault {
+					src = p.sub(defaultValues(name, def, field.Type()), nil)
+				}
+			}
+			paramValue, err := BindE(src, name, field.Type())
+			b.errs.collect(err)
 			if paramValue.Type().ConvertibleTo(field.Type()) {
 				field.Set(paramValue.Convert(field.Type()))
 			}
@@ -209,6 +270,128 @@ func (p *Params) bindValuesToStruct(elm reflect.Value, pointerMap map[uintptr]bo
 	return
 }
 
+// hasParam reports whether any value or file of name present,
+// include keys of slice, map and struct, eg: name[0], name[key], name.Field
+func (p *Params) hasParam(name string) bool {
+	present := func(key string) bool {
+		if key == name {
+			return true
+		}
+		if strings.HasPrefix(key, name) {
+			c := key[len(name)]
+			return c == '[' || c == '.'
+		}
+		return false
+	}
+	for key, vals := range p.Values {
+		if len(vals) > 0 && present(key) {
+			return true
+		}
+	}
+	for key := range p.Files {
+		if present(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultValues makes values of `default` tag, comma separated for slice,
+// eg: `default:"10"`, `default:"a,b"`
+func defaultValues(name, def string, typ reflect.Type) url.Values {
+	if typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8 {
+		return url.Values{name: strings.Split(def, ",")}
+	}
+	return url.Values{name: {def}}
+}
+
+// paramSources are sources of `param` tag option
+var paramSources = map[string]bool{
+	"route":  true,
+	"query":  true,
+	"form":   true,
+	"header": true,
+	"cookie": true,
+}
+
+// checked param tags of struct types, reflect.Type => error
+var paramTagsCache sync.Map
+
+// checkParamTags rejects unknown sources of param tags, once for each struct type
+func checkParamTags(typ reflect.Type) error {
+	if cached, ok := paramTagsCache.Load(typ); ok {
+		err, _ := cached.(error)
+		return err
+	}
+
+	var err error
+	for i := 0; i < typ.NumField() && err == nil; i++ {
+		ftyp := typ.Field(i)
+		if ftyp.Anonymous {
+			embedded := ftyp.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				err = checkParamTags(embedded)
+			}
+			continue
+		}
+		if _, source := parseParamTag(ftyp.Tag.Get("param")); source != "" && !paramSources[source] {
+			err = fmt.Errorf("unknown param source `%s` of %s.%s", source, typ, ftyp.Name)
+		}
+	}
+
+	paramTagsCache.Store(typ, err)
+	return err
+}
+
+// parseParamTag splits `param` tag into name and source option,
+// eg: `param:"id,source=route"`, `param:"X-Tenant-Id,source=header"`
+func parseParamTag(tag string) (name, source string) {
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	for _, opt := range parts[1:] {
+		opt = strings.TrimSpace(opt)
+		if strings.HasPrefix(opt, "source=") {
+			source = opt[len("source="):]
+		}
+	}
+	return
+}
+
+// sourceValues returns the param map of source,
+// one of route, query, form, header and cookie
+func (p *Params) sourceValues(source string) url.Values {
+	switch source {
+	case "header":
+		if p.req == nil {
+			return nil
+		}
+		return url.Values(p.req.Header)
+	case "cookie":
+		if p.req == nil {
+			return nil
+		}
+		values := make(url.Values)
+		for _, c := range p.req.Cookies() {
+			values.Add(c.Name, c.Value)
+		}
+		return values
+	case "route":
+		return p.Route.Values
+	case "query":
+		if p.Query == nil && p.req != nil {
+			p.Query = p.req.URL.Query()
+		}
+		return p.Query
+	case "form":
+		return p.Form
+	}
+	// rejected by checkParamTags
+	return nil
+}
+
 // calcValues returns a unified view of the component param maps.
 func (p *Params) calcValues() url.Values {
 	numParams := len(p.Query) + len(p.Route.Values) + len(p.Form)
@@ -243,7 +426,11 @@ func (p *Params) calcValues() url.Values {
 }
 
 func ParamsParser() interface{} {
-	var maxMemory int64
+	var (
+		maxMemory, maxBodySize int64
+		streamMultipart        bool
+		bodyConfigOnce         sync.Once // loads max_body_size and stream_multipart
+	)
 
 	return func(req *http.Request, ctx strip.Context, log strip.Logger, config *strip.Config) *Params {
 		params := new(Params)
@@ -257,6 +444,8 @@ func ParamsParser() interface{} {
 			params.Route = *routeInfo
 		}
 
+		ctx.Find(&params.Binders, "")
+
 		if maxMemory == 0 {
 			config.Bind(&maxMemory, "max_memory")
 			if maxMemory == 0 {
@@ -264,6 +453,16 @@ func ParamsParser() interface{} {
 			}
 		}
 
+		bodyConfigOnce.Do(func() {
+			config.Bind(&maxBodySize, "max_body_size")
+			if maxBodySize == 0 {
+				maxBodySize = DefaultMaxBodySize
+			}
+			config.Bind(&streamMultipart, "stream_multipart")
+		})
+		params.maxBodySize = maxBodySize
+		params.streamMultipart = streamMultipart
+
 		err := ParseParams(params, maxMemory)
 		if err != nil {
 			log.Notice("parse params error", err)
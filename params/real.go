@@ -1,75 +1,247 @@
 package params
 
 import (
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 )
 
-func RealIp(req *http.Request) (realIp string) {
-	realIp = req.Header.Get("X-Real-Ip")
-	if realIp != "" {
-		return
+// default trusted proxies, loopback only,
+// proxies of private networks should be trusted by SetTrustedProxies
+var defaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+// *ProxyResolver, replaced by SetTrustedProxies while serving requests
+var defaultProxyResolver atomic.Value
+
+func init() {
+	defaultProxyResolver.Store(MustProxyResolver(defaultTrustedProxies...))
+}
+
+// DefaultProxyResolver returns the resolver used by RealIp, RealHost, RealProto, RealURI and RealURL,
+// replace it by SetTrustedProxies
+func DefaultProxyResolver() *ProxyResolver {
+	return defaultProxyResolver.Load().(*ProxyResolver)
+}
+
+// ProxyResolver resolves client address of request, headers set by proxies
+// are used only if the request comes from a trusted proxy.
+type ProxyResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewProxyResolver makes resolver trusts the proxies, each one is CIDR or single ip,
+// eg: 10.0.0.0/8, 192.168.1.1, ::1
+func NewProxyResolver(proxies ...string) (*ProxyResolver, error) {
+	r := &ProxyResolver{}
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: p}
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, err
+		}
+		r.trusted = append(r.trusted, ipNet)
 	}
+	return r, nil
+}
 
-	ips := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
-	if len(ips) > 0 && ips[0] != "" {
-		rip := strings.Split(ips[0], ":")
-		realIp = rip[0]
-		return
+func MustProxyResolver(proxies ...string) *ProxyResolver {
+	r, err := NewProxyResolver(proxies...)
+	if err != nil {
+		panic(err)
 	}
+	return r
+}
+
+// SetTrustedProxies replaces DefaultProxyResolver, no proxy trusted if empty,
+// it is safe to call while serving requests
+func SetTrustedProxies(proxies ...string) error {
+	r, err := NewProxyResolver(proxies...)
+	if err != nil {
+		return err
+	}
+	defaultProxyResolver.Store(r)
+	return nil
+}
 
-	ip := strings.Split(req.RemoteAddr, ":")
-	if len(ip) > 0 {
-		if ip[0] != "[" {
-			realIp = ip[0]
-			return
+// Trusted reports whether ip is a trusted proxy
+func (r *ProxyResolver) Trusted(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, n := range r.trusted {
+		if n.Contains(addr) {
+			return true
 		}
 	}
-	return "127.0.0.1"
+	return false
 }
 
-func RealHost(req *http.Request) (host string) {
-	host = req.Header.Get("X-Forwarded-Host")
-	if host == "" {
-		host = req.Host
+// ClientIp returns the ip of client. Addresses in Forwarded or X-Forwarded-For
+// are walked from the right, the first untrusted one is the client.
+// X-Real-Ip is used if none of them present.
+func (r *ProxyResolver) ClientIp(req *http.Request) string {
+	ip := stripPort(req.RemoteAddr)
+	if !r.Trusted(ip) {
+		return ip
 	}
-	parts := strings.Split(host, ":")
-	if len(parts) > 0 {
-		host = parts[0]
+
+	chain := forwardedValues(req, "for")
+	if len(chain) == 0 {
+		for _, v := range req.Header["X-Forwarded-For"] {
+			for _, s := range strings.Split(v, ",") {
+				chain = append(chain, strings.TrimSpace(s))
+			}
+		}
 	}
-	return
+	if len(chain) == 0 {
+		if realIp := strings.TrimSpace(req.Header.Get("X-Real-Ip")); realIp != "" {
+			return stripPort(realIp)
+		}
+		return ip
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop := stripPort(chain[i])
+		if net.ParseIP(hop) == nil {
+			// obfuscated or unknown, stop at the last known hop
+			return ip
+		}
+		ip = hop
+		if !r.Trusted(ip) {
+			break
+		}
+	}
+	return ip
 }
 
-func RealProto(req *http.Request) (proto string) {
-	proto = req.Header.Get("X-Forwarded-Proto")
-	if proto == "" {
-		proto = req.URL.Scheme
+// Host returns the host without port requested by client
+func (r *ProxyResolver) Host(req *http.Request) string {
+	host := req.Host
+	if r.Trusted(stripPort(req.RemoteAddr)) {
+		if v := firstForwarded(req, "host", "X-Forwarded-Host"); v != "" {
+			host = v
+		}
 	}
-	if proto == "" {
-		proto = "http"
+	return stripPort(host)
+}
+
+// Proto returns the scheme requested by client, http or https
+func (r *ProxyResolver) Proto(req *http.Request) string {
+	if r.Trusted(stripPort(req.RemoteAddr)) {
+		if v := firstForwarded(req, "proto", "X-Forwarded-Proto"); v != "" {
+			return strings.ToLower(v)
+		}
 	}
-	return
+	if req.URL.Scheme != "" {
+		return req.URL.Scheme
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
 }
 
-func RealURI(req *http.Request) (uri string) {
-	uri = req.Header.Get("X-Original-URI")
-	if uri == "" {
-		uri = req.URL.RequestURI()
+// URI returns the request uri before rewritten by proxy
+func (r *ProxyResolver) URI(req *http.Request) string {
+	if r.Trusted(stripPort(req.RemoteAddr)) {
+		if uri := req.Header.Get("X-Original-URI"); uri != "" {
+			return uri
+		}
 	}
-	return uri
+	return req.URL.RequestURI()
 }
 
-func RealURL(req *http.Request) (u *url.URL, err error) {
-	ins, err := url.ParseRequestURI(RealURI(req))
+// URL returns the url requested by client
+func (r *ProxyResolver) URL(req *http.Request) (*url.URL, error) {
+	ins, err := url.ParseRequestURI(r.URI(req))
 	if err != nil {
-		return
+		return nil, err
 	}
 	d := *req.URL
-	d.Host = RealHost(req)
-	d.Scheme = RealProto(req)
+	d.Host = r.Host(req)
+	if strings.Contains(d.Host, ":") {
+		// ipv6
+		d.Host = "[" + d.Host + "]"
+	}
+	d.Scheme = r.Proto(req)
 	d.Opaque = ins.Opaque
 	d.Path = ins.Path
 	d.RawQuery = ins.RawQuery
 	return &d, nil
 }
+
+// forwardedValues returns values of param in every element of RFC 7239 Forwarded header,
+// eg: Forwarded: for=192.0.2.43, for="[2001:db8:cafe::17]:4711";proto=https
+func forwardedValues(req *http.Request, param string) []string {
+	var values []string
+	for _, header := range req.Header["Forwarded"] {
+		for _, elem := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], param) {
+					values = append(values, strings.Trim(kv[1], `"`))
+				}
+			}
+		}
+	}
+	return values
+}
+
+// firstForwarded returns the value set by the first proxy
+func firstForwarded(req *http.Request, param, header string) string {
+	if values := forwardedValues(req, param); len(values) > 0 {
+		return values[0]
+	}
+	v := req.Header.Get(header)
+	if idx := strings.Index(v, ","); idx != -1 {
+		v = v[:idx]
+	}
+	return strings.TrimSpace(v)
+}
+
+// stripPort removes port and brackets of ipv6, eg: [::1]:80 => ::1, 1.1.1.1:80 => 1.1.1.1
+func stripPort(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	// no port
+	return strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+}
+
+func RealIp(req *http.Request) (realIp string) {
+	if realIp = DefaultProxyResolver().ClientIp(req); realIp == "" {
+		realIp = "127.0.0.1"
+	}
+	return
+}
+
+func RealHost(req *http.Request) (host string) {
+	return DefaultProxyResolver().Host(req)
+}
+
+func RealProto(req *http.Request) (proto string) {
+	return DefaultProxyResolver().Proto(req)
+}
+
+func RealURI(req *http.Request) (uri string) {
+	return DefaultProxyResolver().URI(req)
+}
+
+func RealURL(req *http.Request) (u *url.URL, err error) {
+	return DefaultProxyResolver().URL(req)
+}
@@ -0,0 +1,95 @@
+package params
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyResolverClientIp(t *testing.T) {
+	r := MustProxyResolver("10.0.0.0/8", "2001:db8::1")
+
+	cases := []struct {
+		remote  string
+		headers map[string]string
+		ip      string
+	}{
+		{"1.1.1.1:80", map[string]string{"X-Forwarded-For": "2.2.2.2"}, "1.1.1.1"},
+		{"[2001:db8::2]:80", map[string]string{"X-Real-Ip": "2.2.2.2"}, "2001:db8::2"},
+		{"10.0.0.1:80", nil, "10.0.0.1"},
+		{"10.0.0.1:80", map[string]string{"X-Real-Ip": "2.2.2.2"}, "2.2.2.2"},
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "3.3.3.3, 2.2.2.2, 10.0.0.2"}, "2.2.2.2"},
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"[2001:db8::1]:80", map[string]string{"X-Forwarded-For": "2001:db8::3"}, "2001:db8::3"},
+		{"10.0.0.1:80", map[string]string{
+			"Forwarded":       `for=3.3.3.3, for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.2`,
+			"X-Forwarded-For": "4.4.4.4",
+		}, "2001:db8:cafe::17"},
+		{"10.0.0.1:80", map[string]string{"Forwarded": `for=2.2.2.2, for=_hidden`}, "10.0.0.1"},
+		{"10.0.0.1:80", map[string]string{"Forwarded": `For="192.0.2.60:8080"`}, "192.0.2.60"},
+	}
+	for i, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remote
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		assert.Equal(t, c.ip, r.ClientIp(req), "case %d", i)
+	}
+
+	_, err := NewProxyResolver("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = NewProxyResolver("localhost")
+	assert.Error(t, err)
+}
+
+func TestProxyResolverURL(t *testing.T) {
+	r := MustProxyResolver("10.0.0.0/8")
+
+	req := httptest.NewRequest("GET", "/api/users?id=1", nil)
+	req.RemoteAddr = "10.0.0.1:80"
+	req.Header.Set("X-Forwarded-Host", "example.com:8080, proxy.local")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Original-URI", "/users?id=1")
+	u, err := r.URL(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/users?id=1", u.String())
+
+	req.Header.Set("Forwarded", `host="[::1]:8080";proto=http`)
+	u, err = r.URL(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://[::1]/users?id=1", u.String())
+
+	// untrusted
+	req.RemoteAddr = "1.1.1.1:80"
+	req.Host = "origin.com:80"
+	req.TLS = &tls.ConnectionState{}
+	u, err = r.URL(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://origin.com/api/users?id=1", u.String())
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	defer SetTrustedProxies(defaultTrustedProxies...)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:80"
+	req.Header.Set("X-Forwarded-For", "2.2.2.2")
+	assert.Equal(t, "10.0.0.1", RealIp(req), "private networks are not trusted by default")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			RealIp(req)
+		}
+	}()
+	assert.NoError(t, SetTrustedProxies("10.0.0.0/8"))
+	<-done
+	assert.Equal(t, "2.2.2.2", RealIp(req))
+
+	assert.Error(t, SetTrustedProxies("10.0.0.0/33"))
+	assert.Equal(t, "2.2.2.2", RealIp(req), "kept on error")
+}
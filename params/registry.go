@@ -0,0 +1,64 @@
+package params
+
+import (
+	"mime/multipart"
+	"net/url"
+	"reflect"
+)
+
+// BinderRegistry holds binders of one app, looked up before the global
+// TypeBinders and KindBinders. Provide it to the app and ParamsParser uses it:
+//
+//	reg := params.NewBinderRegistry()
+//	reg.RegisterType(reflect.TypeOf(Money{}), moneyBinder)
+//	sp.Provide(reg)
+type BinderRegistry struct {
+	typeBinders map[reflect.Type]Binder
+	kindBinders map[reflect.Kind]Binder
+}
+
+func NewBinderRegistry() *BinderRegistry {
+	return &BinderRegistry{
+		typeBinders: make(map[reflect.Type]Binder),
+		kindBinders: make(map[reflect.Kind]Binder),
+	}
+}
+
+func (r *BinderRegistry) RegisterType(typ reflect.Type, binder Binder) *BinderRegistry {
+	r.typeBinders[typ] = binder
+	return r
+}
+
+func (r *BinderRegistry) RegisterKind(kind reflect.Kind, binder Binder) *BinderRegistry {
+	r.kindBinders[kind] = binder
+	return r
+}
+
+// BindValuesToStruct binds url.Values to struct by binders of registry
+func (r *BinderRegistry) BindValuesToStruct(dest interface{}, values url.Values) error {
+	p := &Params{Values: values, Binders: r}
+	return p.BindValuesToStructE(dest)
+}
+
+// binderFor finds binder in order: type of registry, global type, encoding.TextUnmarshaler,
+// kind of registry, global kind. Nil registry uses globals only.
+func (r *BinderRegistry) binderFor(typ reflect.Type) (Binder, bool) {
+	if r == nil {
+		return binderForType(typ)
+	}
+	if binder, ok := r.typeBinders[typ]; ok {
+		return binder, true
+	}
+	if binder, ok := TypeBinders[typ]; ok {
+		return binder, true
+	}
+	if binder, ok := r.kindBinders[typ.Kind()]; ok && !reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return binder, true
+	}
+	return binderForType(typ)
+}
+
+// sub makes params of values to bind by the same binders
+func (p *Params) sub(values url.Values, files map[string][]*multipart.FileHeader) *Params {
+	return &Params{Values: values, Files: files, Binders: p.Binders}
+}
@@ -0,0 +1,104 @@
+package params
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type upperString string
+
+func TestBinderRegistry(t *testing.T) {
+	type form struct {
+		Name  upperString `param:"name"`
+		Names []upperString
+		Count int `param:"count"`
+	}
+	upper := makeBinder(ValueBinderE(func(val string, typ reflect.Type) (reflect.Value, error) {
+		return reflect.ValueOf(upperString(strings.ToUpper(val))), nil
+	}))
+	half := Binder{Bind: func(params *Params, name string, typ reflect.Type) reflect.Value {
+		v := Bind(&Params{Values: params.Values}, name, typ)
+		return reflect.ValueOf(int(v.Int() / 2))
+	}}
+
+	values := url.Values{"name": {"rob"}, "Names[]": {"a"}, "count": {"10"}}
+
+	reg := NewBinderRegistry().
+		RegisterType(reflect.TypeOf(upperString("")), upper).
+		RegisterKind(reflect.Int, half)
+
+	var f form
+	assert.NoError(t, reg.BindValuesToStruct(&f, values))
+	assert.Equal(t, form{Name: "ROB", Names: []upperString{"A"}, Count: 5}, f)
+
+	// globals untouched
+	f = form{}
+	assert.NoError(t, BindValuesToStructE(&f, values))
+	assert.Equal(t, form{Name: "rob", Names: []upperString{"a"}, Count: 10}, f)
+}
+
+func TestDefaultTag(t *testing.T) {
+	type form struct {
+		Page    int               `param:"page" default:"1"`
+		PerPage int               `param:"per_page" default:"20"`
+		Sort    []string          `param:"sort" default:"-created,name"`
+		Timeout time.Duration     `param:"timeout" default:"1m30s"`
+		Opts    map[string]string `param:"opts" default:"{\"a\":\"b\"}"`
+		Host    string            `param:"X-Host,source=header" default:"localhost"`
+	}
+
+	var f form
+	assert.NoError(t, BindValuesToStructE(&f, url.Values{"per_page": {"50"}}))
+	assert.Equal(t, form{
+		Page:    1,
+		PerPage: 50,
+		Sort:    []string{"-created", "name"},
+		Timeout: 90 * time.Second,
+		Opts:    map[string]string{"a": "b"},
+		Host:    "localhost",
+	}, f)
+
+	f = form{}
+	assert.NoError(t, BindValuesToStructE(&f, url.Values{"sort[]": {"id"}, "opts[x]": {"y"}, "timeout": {"1000"}}))
+	assert.Equal(t, []string{"id"}, f.Sort)
+	assert.Equal(t, map[string]string{"x": "y"}, f.Opts)
+	assert.Equal(t, time.Microsecond, f.Timeout)
+
+	// invalid default is reported
+	type bad struct {
+		Page int `param:"page" default:"one"`
+	}
+	assert.Error(t, BindValuesToStructE(&bad{}, nil))
+}
+
+func TestTextUnmarshalerBinder(t *testing.T) {
+	type form struct {
+		Ip    net.IP    `param:"ip"`
+		Ips   []net.IP  `param:"ips"`
+		At    time.Time `param:"at"`
+		Ptr   *net.IP   `param:"ptr"`
+		Wrong net.IP    `param:"wrong"`
+	}
+
+	var f form
+	err := BindValuesToStructE(&f, url.Values{
+		"ip":    {"::1"},
+		"ips[]": {"10.0.0.1"},
+		"at":    {"2020-01-02"},
+		"ptr":   {"1.1.1.1"},
+		"wrong": {"x.x"},
+	})
+	if assert.IsType(t, BindErrors{}, err) {
+		assert.Equal(t, "wrong", err.(BindErrors)[0].Key)
+	}
+	assert.Equal(t, "::1", f.Ip.String())
+	assert.Equal(t, "10.0.0.1", f.Ips[0].String())
+	assert.Equal(t, 2020, f.At.Year())
+	assert.Equal(t, "1.1.1.1", f.Ptr.String())
+}
@@ -0,0 +1,178 @@
+package params
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/wujiu2020/strip/utils/apires"
+)
+
+const sniffLen = 512
+
+var (
+	ErrFileTooLarge   = apires.NewResError(http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge, "upload file too large")
+	ErrUploadTooLarge = apires.NewResError(http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge, "upload too large")
+	ErrFileType       = apires.NewResError(http.StatusUnsupportedMediaType, http.StatusUnsupportedMediaType, "file type not allowed")
+	ErrNotMultipart   = apires.NewResError(http.StatusBadRequest, http.StatusBadRequest, "request is not multipart")
+
+	errMultipartParsed = errors.New("multipart body already parsed, set `stream_multipart` of config to stream upload")
+)
+
+// UploadOption limits the streaming upload
+type UploadOption struct {
+	MaxFileSize  int64    // bytes of each file, unlimited if 0
+	MaxTotalSize int64    // bytes of the whole body, DefaultMaxBodySize if 0
+	MaxFormSize  int64    // bytes of each non-file field, 1 MB if 0
+	AllowedTypes []string // sniffed MIME types allowed, prefix ends with "/" matches the group, eg: image/
+}
+
+// UploadFile describes a file part of multipart body
+type UploadFile struct {
+	FieldName   string
+	FileName    string
+	ContentType string // sniffed by http.DetectContentType
+	Header      textproto.MIMEHeader
+}
+
+// StreamUpload walks parts of multipart body without spooling to disk, fn is called
+// with the reader of each file in order. Non-file fields are added to p.Form and
+// p.Values, so fields after files are only available after StreamUpload returns.
+//
+// ParamsParser parses multipart body before the action, set `stream_multipart`
+// of config to leave the body for StreamUpload.
+func (p *Params) StreamUpload(opt UploadOption, fn func(file *UploadFile, r io.Reader) error) error {
+	if p.req.MultipartForm != nil {
+		return errMultipartParsed
+	}
+
+	form, err := StreamUpload(p.req, opt, fn)
+	if p.Form == nil {
+		p.Form = make(url.Values, len(form))
+	}
+	for k, v := range form {
+		p.Form[k] = append(p.Form[k], v...)
+	}
+	p.Values = p.calcValues()
+	return err
+}
+
+// StreamUpload walks parts of multipart body of req, returns the non-file fields.
+func StreamUpload(req *http.Request, opt UploadOption, fn func(file *UploadFile, r io.Reader) error) (url.Values, error) {
+	mediaType, ps, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != multipartFormContentType || ps["boundary"] == "" {
+		return nil, ErrNotMultipart
+	}
+
+	total := opt.MaxTotalSize
+	if total <= 0 {
+		total = DefaultMaxBodySize
+	}
+	maxForm := opt.MaxFormSize
+	if maxForm <= 0 {
+		maxForm = 1 << 20
+	}
+
+	body := &limitedReader{r: req.Body, n: total}
+	mr := multipart.NewReader(body, ps["boundary"])
+	form := make(url.Values)
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return form, uploadError(body, err)
+		}
+
+		if part.FileName() == "" {
+			b, err := ioutil.ReadAll(io.LimitReader(part, maxForm+1))
+			if err != nil {
+				return form, uploadError(body, err)
+			}
+			if int64(len(b)) > maxForm {
+				return form, ErrUploadTooLarge.WithMsgf("form field `%s` too large", part.FormName())
+			}
+			form.Add(part.FormName(), string(b))
+			continue
+		}
+
+		if err := streamFile(part, body, opt, fn); err != nil {
+			return form, err
+		}
+	}
+}
+
+func streamFile(part *multipart.Part, body *limitedReader, opt UploadOption, fn func(*UploadFile, io.Reader) error) error {
+	br := bufio.NewReaderSize(part, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return uploadError(body, err)
+	}
+
+	file := &UploadFile{
+		FieldName:   part.FormName(),
+		FileName:    part.FileName(),
+		ContentType: http.DetectContentType(head),
+		Header:      part.Header,
+	}
+	if !typeAllowed(file.ContentType, opt.AllowedTypes) {
+		return ErrFileType.WithMsgf("file type `%s` of `%s` not allowed", file.ContentType, file.FileName)
+	}
+
+	var (
+		r  io.Reader = br
+		fr *limitedReader
+	)
+	if opt.MaxFileSize > 0 {
+		fr = &limitedReader{r: br, n: opt.MaxFileSize}
+		r = fr
+	}
+
+	err = fn(file, r)
+	if err == nil {
+		// unread bytes count to the limit too
+		_, err = io.Copy(ioutil.Discard, r)
+	}
+	switch {
+	case fr != nil && fr.n < 0:
+		return ErrFileTooLarge.WithMsgf("file `%s` too large", file.FileName)
+	case body.n < 0:
+		return ErrUploadTooLarge
+	}
+	if err != nil && err == errBodyTooLarge {
+		return ErrUploadTooLarge
+	}
+	return err
+}
+
+func uploadError(body *limitedReader, err error) error {
+	if err == errBodyTooLarge || body.n < 0 {
+		return ErrUploadTooLarge
+	}
+	return ErrInvalidBody.WithMsgf("read multipart body err: %v", err)
+}
+
+func typeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	// drop params, eg: text/plain; charset=utf-8
+	if idx := strings.Index(contentType, ";"); idx != -1 {
+		contentType = contentType[:idx]
+	}
+	for _, t := range allowed {
+		if t == contentType || strings.HasSuffix(t, "/") && strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package params
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip/utils/apires"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A")
+
+func newUploadParams(t *testing.T, build func(mw *multipart.Writer)) *Params {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	build(mw)
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/upload?dir=a", buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	p := &Params{req: req, streamMultipart: true}
+	assert.NoError(t, ParseParams(p, 0))
+	assert.Nil(t, req.MultipartForm)
+	return p
+}
+
+func writeFile(mw *multipart.Writer, field, name string, data []byte) {
+	w, _ := mw.CreateFormFile(field, name)
+	w.Write(data)
+}
+
+func TestStreamUpload(t *testing.T) {
+	p := newUploadParams(t, func(mw *multipart.Writer) {
+		mw.WriteField("title", "pics")
+		writeFile(mw, "file", "a.png", append(pngHeader, make([]byte, 1000)...))
+		writeFile(mw, "file", "b.txt", []byte("hello"))
+		mw.WriteField("tag", "x")
+	})
+
+	type upload struct {
+		name, field, contentType string
+		size                     int
+	}
+	var uploads []upload
+	err := p.StreamUpload(UploadOption{}, func(file *UploadFile, r io.Reader) error {
+		b, err := ioutil.ReadAll(r)
+		uploads = append(uploads, upload{file.FileName, file.FieldName, file.ContentType, len(b)})
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []upload{
+		{"a.png", "file", "image/png", 1008},
+		{"b.txt", "file", "text/plain; charset=utf-8", 5},
+	}, uploads)
+	assert.Equal(t, "pics", p.Get("title"))
+	assert.Equal(t, "x", p.Get("tag"))
+	assert.Equal(t, "a", p.Get("dir"))
+}
+
+func TestStreamUploadLimits(t *testing.T) {
+	build := func(mw *multipart.Writer) {
+		writeFile(mw, "file", "a.png", append(pngHeader, make([]byte, 1000)...))
+		writeFile(mw, "file", "b.txt", []byte(strings.Repeat("a", 2000)))
+	}
+	discard := func(file *UploadFile, r io.Reader) error {
+		_, err := io.Copy(ioutil.Discard, r)
+		return err
+	}
+	// read part of file only
+	peek := func(file *UploadFile, r io.Reader) error {
+		_, err := r.Read(make([]byte, 10))
+		return err
+	}
+
+	cases := []struct {
+		opt UploadOption
+		fn  func(*UploadFile, io.Reader) error
+		err *apires.ResError
+		msg string
+	}{
+		{UploadOption{MaxFileSize: 1500}, discard, ErrFileTooLarge, "file `b.txt` too large"},
+		{UploadOption{MaxFileSize: 1500}, peek, ErrFileTooLarge, "file `b.txt` too large"},
+		{UploadOption{MaxTotalSize: 2000}, discard, ErrUploadTooLarge, "upload too large"},
+		{UploadOption{AllowedTypes: []string{"image/"}}, discard, ErrFileType, "file type `text/plain; charset=utf-8` of `b.txt` not allowed"},
+		{UploadOption{AllowedTypes: []string{"image/png", "text/plain"}}, discard, nil, ""},
+	}
+	for i, c := range cases {
+		p := newUploadParams(t, build)
+		err := p.StreamUpload(c.opt, c.fn)
+		if c.err == nil {
+			assert.NoError(t, err, "case %d", i)
+			continue
+		}
+		if assert.IsType(t, &apires.ResError{}, err, "case %d", i) {
+			assert.True(t, c.err.EqualAny(err.(*apires.ResError)), "case %d: %v", i, err)
+			assert.Equal(t, c.msg, err.(*apires.ResError).Message, "case %d", i)
+		}
+	}
+
+	// parsed by ParamsParser
+	p := newUploadParams(t, build)
+	p.streamMultipart = false
+	assert.NoError(t, ParseParams(p, 0))
+	assert.Error(t, p.StreamUpload(UploadOption{}, discard))
+}
@@ -0,0 +1,435 @@
+package params
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wujiu2020/strip/utils/apires"
+)
+
+var (
+	// ErrValidation is the base response of failed validation, Data lists every failing field
+	ErrValidation = apires.NewResError(http.StatusUnprocessableEntity, http.StatusUnprocessableEntity, "validation failed")
+
+	emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+$`)
+
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// FieldError describes a field failed one rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors lists every failing field
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ValidationErrors) has(field string) bool {
+	for _, fe := range e {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ResError converts to a standard 422 response
+func (e ValidationErrors) ResError() *apires.ResError {
+	return ErrValidation.WithData(e, ErrValidation.Message)
+}
+
+// BindAndValidate binds values to struct then validates it by `validate` tag,
+// returns ValidationErrors when any field failed, values can not be parsed
+// are reported as rule `type`
+func (p *Params) BindAndValidate(dest interface{}) error {
+	var errs ValidationErrors
+	if err := p.BindValuesToStructE(dest); err != nil {
+		bindErrs, ok := err.(BindErrors)
+		if !ok {
+			return err
+		}
+		for _, be := range bindErrs {
+			fe := &FieldError{Field: be.Key, Rule: "type", Message: fmt.Sprintf("invalid value %q", be.Value)}
+			if be.Type != nil {
+				fe.Param = be.Type.String()
+			}
+			errs = append(errs, fe)
+		}
+	}
+
+	if err := Validate(dest); err != nil {
+		verrs, ok := err.(ValidationErrors)
+		if !ok {
+			return err
+		}
+		for _, fe := range verrs {
+			if !errs.has(fe.Field) {
+				errs = append(errs, fe)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// BindAndValidate binds url.Values to struct then validates it
+func BindAndValidate(dest interface{}, values map[string][]string) error {
+	p := &Params{Values: values}
+	return p.BindAndValidate(dest)
+}
+
+// Validate checks struct fields by `validate` tag, rules separated by comma:
+//
+//	required     value must not be zero
+//	omitempty    zero value skips the other rules
+//	min=N max=N  number range, or length of string / slice / map
+//	len=N        exact length of string / slice / map
+//	oneof=a b c  value must be one of space separated values
+//	email        string is an email address
+//	regexp=expr  string matches expr, must be the last rule
+//
+// Zero values are checked by all rules unless omitempty, nil pointers are checked by required only.
+// Nested structs and elements of slices are validated recursively.
+// Field names follow the binding keys, eg: user.Name, addresses[2].Zip
+//
+// Tags are parsed once for each struct type, invalid ones are returned as error
+// instead of ValidationErrors.
+func Validate(v interface{}) error {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		panic("need struct or ptr of struct")
+	}
+
+	var errs ValidationErrors
+	if err := validateStruct(val, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// fieldRules are parsed tags of a struct field
+type fieldRules struct {
+	index     int
+	name      string // binding key of the field
+	fieldName string
+	anonymous bool
+	rules     []rule
+	required  bool
+	omitempty bool
+}
+
+// parsed rules of struct types, reflect.Type => []fieldRules
+var structRulesCache sync.Map
+
+func structRules(typ reflect.Type) ([]fieldRules, error) {
+	if cached, ok := structRulesCache.Load(typ); ok {
+		return cached.([]fieldRules), nil
+	}
+
+	var fields []fieldRules
+	for i := 0; i < typ.NumField(); i++ {
+		ftyp := typ.Field(i)
+		if ftyp.PkgPath != "" && !ftyp.Anonymous { // skip unexport
+			continue
+		}
+		if ftyp.Anonymous {
+			fields = append(fields, fieldRules{index: i, anonymous: true})
+			continue
+		}
+
+		tag := ftyp.Tag.Get("param")
+		if tag == "-" {
+			continue
+		}
+		name := tag
+		if idx := strings.Index(tag, ","); idx != -1 {
+			name = tag[:idx]
+		}
+
+		rules, err := parseRules(ftyp.Tag.Get("validate"))
+		if err != nil {
+			return nil, fmt.Errorf("validate tag of %s.%s: %v", typ, ftyp.Name, err)
+		}
+		f := fieldRules{index: i, name: name, fieldName: ftyp.Name}
+		for _, r := range rules {
+			switch r.name {
+			case "required":
+				f.required = true
+			case "omitempty":
+				f.omitempty = true
+			default:
+				f.rules = append(f.rules, r)
+			}
+		}
+		fields = append(fields, f)
+	}
+
+	structRulesCache.Store(typ, fields)
+	return fields, nil
+}
+
+func validateStruct(val reflect.Value, prefix string, errs *ValidationErrors) error {
+	fields, err := structRules(val.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		field := val.Field(f.index)
+
+		// embedded fields are promoted
+		if f.anonymous {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+			if field.Kind() == reflect.Struct {
+				if err := validateStruct(field, prefix, errs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		name := f.name
+		if name == "" || prefix != "" {
+			// fields of nested struct bound by field name
+			name = f.fieldName
+		}
+		if err := validateField(field, prefix+name, f, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateField(field reflect.Value, name string, f fieldRules, errs *ValidationErrors) error {
+	if isZero(field) {
+		if f.required {
+			*errs = append(*errs, (rule{name: "required"}).fail(name, "is required"))
+			return nil
+		}
+		if f.omitempty || field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+			return nil
+		}
+	}
+
+	for field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+		field = field.Elem()
+	}
+
+	for _, r := range f.rules {
+		if fe := r.check(field, name); fe != nil {
+			*errs = append(*errs, fe)
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.Struct:
+		if field.Type() != timeType {
+			return validateStruct(field, name+".", errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			elem := field.Index(i)
+			for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Struct && elem.Type() != timeType {
+				if err := validateStruct(elem, name+"["+strconv.Itoa(i)+"].", errs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type rule struct {
+	name  string
+	param string
+
+	limit   float64 // of min, max, len
+	options []string
+	re      *regexp.Regexp
+}
+
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regexp=") {
+			// pattern may contain comma
+			part, tag = tag, ""
+		} else if idx := strings.Index(tag, ","); idx != -1 {
+			part, tag = tag[:idx], tag[idx+1:]
+		} else {
+			part, tag = tag, ""
+		}
+
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r := rule{name: part}
+		if idx := strings.Index(part, "="); idx != -1 {
+			r.name, r.param = part[:idx], part[idx+1:]
+		}
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// compile checks and parses param of the rule
+func (r *rule) compile() (err error) {
+	switch r.name {
+	case "required", "omitempty", "email":
+		if r.param != "" {
+			return fmt.Errorf("rule `%s` takes no param", r.name)
+		}
+	case "min", "max":
+		if r.limit, err = strconv.ParseFloat(r.param, 64); err != nil {
+			return fmt.Errorf("invalid param of rule `%s=%s`", r.name, r.param)
+		}
+	case "len":
+		n, err := strconv.Atoi(r.param)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid param of rule `len=%s`", r.param)
+		}
+		r.limit = float64(n)
+	case "oneof":
+		if r.options = strings.Fields(r.param); len(r.options) == 0 {
+			return fmt.Errorf("rule `oneof` needs values")
+		}
+	case "regexp":
+		if r.re, err = regexp.Compile(r.param); err != nil {
+			return fmt.Errorf("invalid param of rule `regexp=%s`: %v", r.param, err)
+		}
+	default:
+		return fmt.Errorf("unknown rule `%s`", r.name)
+	}
+	return nil
+}
+
+func (r rule) fail(field, format string, args ...interface{}) *FieldError {
+	return &FieldError{
+		Field:   field,
+		Rule:    r.name,
+		Param:   r.param,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (r rule) check(val reflect.Value, name string) *FieldError {
+	switch r.name {
+	case "min", "max":
+		n, isLen := measure(val)
+		if r.name == "min" && n < r.limit {
+			if isLen {
+				return r.fail(name, "length must be at least %s", r.param)
+			}
+			return r.fail(name, "must be at least %s", r.param)
+		}
+		if r.name == "max" && n > r.limit {
+			if isLen {
+				return r.fail(name, "length must be at most %s", r.param)
+			}
+			return r.fail(name, "must be at most %s", r.param)
+		}
+
+	case "len":
+		if n, _ := measure(val); n != r.limit {
+			return r.fail(name, "length must be %s", r.param)
+		}
+
+	case "oneof":
+		s := fmt.Sprint(val.Interface())
+		for _, option := range r.options {
+			if s == option {
+				return nil
+			}
+		}
+		return r.fail(name, "must be one of [%s]", r.param)
+
+	case "email":
+		if val.Kind() != reflect.String || !emailRegexp.MatchString(val.String()) {
+			return r.fail(name, "must be a valid email address")
+		}
+
+	case "regexp":
+		if val.Kind() != reflect.String || !r.re.MatchString(val.String()) {
+			return r.fail(name, "must match %s", r.param)
+		}
+	}
+	return nil
+}
+
+// measure returns number value, or length of string / slice / map
+func measure(val reflect.Value) (n float64, isLen bool) {
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), false
+	case reflect.String:
+		return float64(utf8.RuneCountInString(val.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(val.Len()), true
+	}
+	return 0, false
+}
+
+func isZero(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return val.IsNil()
+	case reflect.Map, reflect.Slice, reflect.String:
+		return val.Len() == 0
+	case reflect.Struct:
+		if t, ok := val.Interface().(time.Time); ok {
+			return t.IsZero()
+		}
+		return false
+	}
+	return reflect.DeepEqual(val.Interface(), reflect.Zero(val.Type()).Interface())
+}
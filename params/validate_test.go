@@ -0,0 +1,137 @@
+package params
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	type address struct {
+		Zip string `validate:"required,len=6"`
+	}
+
+	type user struct {
+		Name      string    `param:"name" validate:"required,min=2,max=5"`
+		Age       int       `param:"age" validate:"min=18,max=60"`
+		Email     string    `param:"email" validate:"email"`
+		Role      string    `param:"role" validate:"oneof=admin guest"`
+		Code      string    `param:"code" validate:"regexp=^[a-z]{2,3}$"`
+		Tags      []string  `param:"tags" validate:"max=2"`
+		Optional  *int      `param:"optional" validate:"min=1"`
+		Addresses []address `param:"addresses"`
+		Profile   address   `param:"profile"`
+	}
+
+	values := url.Values{
+		"name":             {"x"},
+		"age":              {"16"},
+		"email":            {"not-an-email"},
+		"role":             {"root"},
+		"code":             {"abcd"},
+		"tags[]":           {"a", "b", "c"},
+		"addresses[0].Zip": {"100000"},
+		"addresses[1].Zip": {"1"},
+		"profile.Zip":      {"200000"},
+	}
+
+	var u user
+	err := BindAndValidate(&u, values)
+	errs, ok := err.(ValidationErrors)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	failed := make(map[string]string)
+	for _, fe := range errs {
+		failed[fe.Field] = fe.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"name":             "min",
+		"age":              "min",
+		"email":            "email",
+		"role":             "oneof",
+		"code":             "regexp",
+		"tags":             "max",
+		"addresses[1].Zip": "len",
+	}, failed)
+
+	values = url.Values{
+		"name":        {"rob"},
+		"age":         {"20"},
+		"email":       {"rob@example.com"},
+		"role":        {"admin"},
+		"code":        {"ab"},
+		"profile.Zip": {"200000"},
+	}
+	u = user{}
+	assert.NoError(t, BindAndValidate(&u, values))
+
+	// required of nested struct
+	values.Del("profile.Zip")
+	u = user{}
+	err = BindAndValidate(&u, values)
+	if assert.Error(t, err) {
+		errs = err.(ValidationErrors)
+		assert.Len(t, errs, 1)
+		assert.Equal(t, "profile.Zip", errs[0].Field)
+		assert.Equal(t, "required", errs[0].Rule)
+
+		res := errs.ResError()
+		assert.Equal(t, http.StatusUnprocessableEntity, res.HttpCode())
+
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(res.Error()), &body))
+		assert.Equal(t, "validation failed", body["message"])
+		assert.Len(t, body["data"], 1)
+	}
+}
+
+func TestValidateZeroValue(t *testing.T) {
+	type form struct {
+		Age  int    `param:"age" validate:"min=18"`
+		Role string `param:"role" validate:"oneof=admin guest"`
+		Code string `param:"code" validate:"len=2"`
+		Note string `param:"note" validate:"omitempty,min=3"`
+	}
+
+	err := Validate(&form{})
+	errs, ok := err.(ValidationErrors)
+	if !assert.True(t, ok, "%v", err) {
+		return
+	}
+	failed := make(map[string]string)
+	for _, fe := range errs {
+		failed[fe.Field] = fe.Rule
+	}
+	assert.Equal(t, map[string]string{"age": "min", "role": "oneof", "code": "len"}, failed)
+
+	assert.NoError(t, Validate(&form{Age: 18, Role: "guest", Code: "ab"}))
+	assert.Error(t, Validate(&form{Age: 18, Role: "guest", Code: "ab", Note: "x"}))
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	type unknown struct {
+		Name string `validate:"requird"`
+	}
+	type badParam struct {
+		Age int `validate:"min=ten"`
+	}
+	type nested struct {
+		Inner badParam
+	}
+
+	for _, v := range []interface{}{&unknown{}, &badParam{}, &nested{}} {
+		err := Validate(v)
+		if assert.Error(t, err) {
+			_, ok := err.(ValidationErrors)
+			assert.False(t, ok, "%v", err)
+		}
+	}
+
+	err := BindAndValidate(&unknown{}, url.Values{})
+	assert.Contains(t, err.Error(), "unknown rule `requird`")
+}
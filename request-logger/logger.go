@@ -3,11 +3,11 @@ package reqlogger
 import (
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/wujiu2020/strip"
 	"github.com/wujiu2020/strip/inject"
+	"github.com/wujiu2020/strip/params"
 	"github.com/wujiu2020/strip/utils"
 )
 
@@ -30,6 +30,9 @@ type LoggerOption struct {
 	PrefixFilter  LoggerContentFilter
 	ReqBegFilter  LoggerContentFilter
 	ReqEndFilter  LoggerContentFilter
+
+	// resolves client ip by trusted proxies, params.DefaultProxyResolver() if nil
+	ProxyResolver *params.ProxyResolver
 }
 
 // ReqLogger filter
@@ -74,7 +77,7 @@ func ReqLoggerFilter(out strip.LogPrinter, opts ...LoggerOption) inject.Provider
 
 		req = setLoggerInContext(ctx, req, log)
 
-		remoteAddr := realIp(req)
+		remoteAddr := realIp(opt.ProxyResolver, req)
 
 		start := time.Now()
 		reqBeg := fmt.Sprintf("[REQ_BEG] %s %s%s %s", req.Method, req.Host, req.URL, remoteAddr)
@@ -110,22 +113,11 @@ func ReqLoggerFilter(out strip.LogPrinter, opts ...LoggerOption) inject.Provider
 	}
 }
 
-func realIp(req *http.Request) string {
-	if ip := req.Header.Get(HeaderXRealIp); ip != "" {
-		return strings.TrimSpace(ip)
-	}
-
-	parts := strings.Split(req.Header.Get(HeaderXForwardedFor), ",")
-	if len(parts) > 0 && parts[0] != "" {
-		parts = strings.Split(parts[0], ":")
-		return strings.TrimSpace(parts[0])
-	}
-
-	host := req.RemoteAddr
-	if idx := strings.LastIndex(req.RemoteAddr, ":"); idx != -1 {
-		host = host[:idx]
+func realIp(resolver *params.ProxyResolver, req *http.Request) string {
+	if resolver == nil {
+		resolver = params.DefaultProxyResolver()
 	}
-	return host
+	return resolver.ClientIp(req)
 }
 
 func setLoggerInContext(ctx strip.Context, req *http.Request, log strip.Logger) *http.Request {
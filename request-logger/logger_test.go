@@ -5,33 +5,56 @@ import (
 	"testing"
 
 	"github.com/wujiu2020/strip"
+	"github.com/wujiu2020/strip/params"
 )
 
 func Test_RealIp(t *testing.T) {
 	assert := &strip.Assert{T: t}
 
 	ip := "1.1.1.1"
+	proxy := "10.0.0.1:3000"
+	private := params.MustProxyResolver("10.0.0.0/8")
 
 	req, _ := http.NewRequest("GET", "/", nil)
-	req.Header.Set(HeaderXRealIp, ip)
+	req.RemoteAddr = proxy
+	req.Header.Set(HeaderXRealIp, " "+ip+" ")
+	assert.True(realIp(private, req) == ip)
+
+	// private networks are not trusted by default
+	assert.True(realIp(nil, req) == "10.0.0.1")
 
 	req, _ = http.NewRequest("GET", "/", nil)
-	req.Header.Set(HeaderXRealIp, " "+ip+" ")
-	assert.True(realIp(req) == ip)
+	req.RemoteAddr = proxy
+	req.Header.Set(HeaderXForwardedFor, "0.0.0.0,"+ip+",10.0.0.2")
+	assert.True(realIp(private, req) == ip)
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.RemoteAddr = proxy
+	req.Header.Set(HeaderXForwardedFor, "  0.0.0.0 , "+ip+" , 10.0.0.2")
+	assert.True(realIp(private, req) == ip)
 
 	req, _ = http.NewRequest("GET", "/", nil)
-	req.Header.Set(HeaderXForwardedFor, ip+",0.0.0.0,2.2.2.2")
-	assert.True(realIp(req) == ip)
+	req.RemoteAddr = "127.0.0.1:3000"
+	req.Header.Set(HeaderXRealIp, ip)
+	assert.True(realIp(nil, req) == ip)
 
+	// headers from untrusted address are ignored
 	req, _ = http.NewRequest("GET", "/", nil)
-	req.Header.Set(HeaderXForwardedFor, "  "+ip+" , 0.0.0.0, 2.2.2.2")
-	assert.True(realIp(req) == ip)
+	req.RemoteAddr = ip + ":3000"
+	req.Header.Set(HeaderXRealIp, "2.2.2.2")
+	assert.True(realIp(nil, req) == ip)
 
 	req, _ = http.NewRequest("GET", "/", nil)
 	req.RemoteAddr = ip + ":"
-	assert.True(realIp(req) == ip)
+	assert.True(realIp(nil, req) == ip)
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "[::1]:80"
+	assert.True(realIp(nil, req) == "::1")
 
+	resolver := params.MustProxyResolver("1.1.1.0/24")
 	req, _ = http.NewRequest("GET", "/", nil)
 	req.RemoteAddr = ip + ":3000"
-	assert.True(realIp(req) == ip)
+	req.Header.Set(HeaderXRealIp, "2.2.2.2")
+	assert.True(realIp(resolver, req) == "2.2.2.2")
 }
@@ -60,6 +60,11 @@ func (rw *responseWriter) WriteHeader(s int) {
 }
 
 func (rw *responseWriter) Write(b []byte) (int, error) {
+	// connection has been taken over, nothing can be written
+	if rw.hijacked {
+		return 0, http.ErrHijacked
+	}
+
 	rw.once.Do(func() {
 		rw.callBefore()
 		// The status will be StatusOK if WriteHeader has not been called yet
@@ -67,10 +72,6 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 			rw.status = http.StatusOK
 		}
 
-		if rw.hijacked {
-			return
-		}
-
 		rw.ResponseWriter.WriteHeader(rw.status)
 	})
 
@@ -106,6 +107,11 @@ func (rw *responseWriter) Hijack() (rwc net.Conn, br *bufio.ReadWriter, err erro
 		return
 	}
 	rw.hijacked = true
+
+	// mark as written, let the filters know response has done
+	if rw.status == 0 {
+		rw.status = http.StatusSwitchingProtocols
+	}
 	return
 }
 
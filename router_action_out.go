@@ -40,51 +40,56 @@ func GenericOutFilter() interface{} {
 
 		var rw http.ResponseWriter
 		ctx.Find(&rw)
+		WriteActionOut(ctx, rw, req)
+	}
+}
 
-		var res ActionOut
-		ctx.Find(&res, "")
-		if res == nil {
-			return
-		}
-
-		out := res.Out()
-		if len(out) == 0 {
-			return
-		}
-
-		var body reflect.Value
-		if out[len(out)-1].Kind() == reflect.Int {
-			code := out[len(out)-1].Int()
-			rw.WriteHeader(int(code))
+// WriteActionOut writes result of the action to rw as GenericOutFilter does,
+// for filters need the full response before GenericOutFilter returns, eg: caches of response
+func WriteActionOut(ctx Context, rw http.ResponseWriter, req *http.Request) {
+	var res ActionOut
+	ctx.Find(&res, "")
+	if res == nil {
+		return
+	}
 
-			if len(out) == 1 {
-				return
-			}
+	out := res.Out()
+	if len(out) == 0 {
+		return
+	}
 
-			body = out[len(out)-2]
-		} else {
-			body = out[len(out)-1]
-		}
+	var body reflect.Value
+	if out[len(out)-1].Kind() == reflect.Int {
+		code := out[len(out)-1].Int()
+		rw.WriteHeader(int(code))
 
-		if !body.CanInterface() || body.IsNil() {
+		if len(out) == 1 {
 			return
 		}
 
-		itf := body.Interface()
-		switch src := itf.(type) {
-		case ActionResult:
-			src.Write(ctx, rw, req)
-		case http.Handler:
-			src.ServeHTTP(rw, req)
-		case io.ReadCloser:
-			defer src.Close()
-			io.Copy(rw, src)
-		case io.Reader:
-			io.Copy(rw, src)
-		case string:
-			rw.Write([]byte(src))
-		case []byte:
-			rw.Write(src)
-		}
+		body = out[len(out)-2]
+	} else {
+		body = out[len(out)-1]
+	}
+
+	if !body.CanInterface() || body.IsNil() {
+		return
+	}
+
+	itf := body.Interface()
+	switch src := itf.(type) {
+	case ActionResult:
+		src.Write(ctx, rw, req)
+	case http.Handler:
+		src.ServeHTTP(rw, req)
+	case io.ReadCloser:
+		defer src.Close()
+		io.Copy(rw, src)
+	case io.Reader:
+		io.Copy(rw, src)
+	case string:
+		rw.Write([]byte(src))
+	case []byte:
+		rw.Write(src)
 	}
 }
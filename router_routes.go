@@ -0,0 +1,98 @@
+package strip
+
+import (
+	"reflect"
+	"sort"
+)
+
+// RouteDesc describes a registered route action
+type RouteDesc struct {
+	Method string   // http method, `*` for All and `**` for Any
+	Path   string   // route path, eg: /users/:id
+	Params []string // names of route params in order
+
+	Controller interface{}  // function or struct of the action
+	Action     string       // method name of struct controller
+	Func       reflect.Type // type of action func, nil if action decided at runtime
+}
+
+// Routes returns all registered route actions, ordered by path and method
+func (s *Strip) Routes() []RouteDesc {
+	descs := make([]RouteDesc, 0)
+	s.route.route.walk(nil, func(r *route, params []string) {
+		descs = append(descs, r.describe(params)...)
+	})
+	return descs
+}
+
+func (r *route) walk(params []string, fn func(*route, []string)) {
+	if r.pathParam.isParam || r.pathParam.isWild {
+		params = append(params[:len(params):len(params)], r.pathParam.paramName)
+	}
+
+	if r.isEnd {
+		fn(r, params)
+	}
+
+	keys := make([]string, 0, len(r.pathRoutes))
+	for key := range r.pathRoutes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		r.pathRoutes[key].walk(params, fn)
+	}
+	for _, rt := range r.paramRoutes {
+		rt.walk(params, fn)
+	}
+	if r.wildRoute != nil {
+		r.wildRoute.walk(params, fn)
+	}
+}
+
+func (r *route) describe(params []string) []RouteDesc {
+	path := r.calcPath()
+
+	descs := make([]RouteDesc, 0, len(r.action))
+	for _, m := range methods {
+		act := r.action[m]
+		if act == nil {
+			continue
+		}
+
+		// HEAD is served by GET route by default
+		if m == HEAD && act == r.action[GET] {
+			continue
+		}
+		descs = append(descs, act.describe(m, path, params))
+	}
+
+	if r.allRoute != nil {
+		descs = append(descs, r.allRoute.describe(method("*"), path, params))
+	}
+	if r.anyRoute != nil {
+		descs = append(descs, r.anyRoute.describe(method("**"), path, params))
+	}
+	return descs
+}
+
+func (r *routerAction) describe(m method, path string, params []string) RouteDesc {
+	desc := RouteDesc{
+		Method:     string(m),
+		Path:       path,
+		Params:     params,
+		Controller: r.controller.value,
+	}
+
+	if r.controller.isFunc() {
+		desc.Func = r.controller.val.Type()
+		return desc
+	}
+
+	desc.Action = r.action
+	if fn := reflect.New(r.controller.typ).MethodByName(r.action); fn.IsValid() {
+		desc.Func = fn.Type()
+	}
+	return desc
+}
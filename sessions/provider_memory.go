@@ -0,0 +1,25 @@
+package sessions
+
+import (
+	"github.com/wujiu2020/strip/caches"
+)
+
+type MemoryConfig struct {
+	Config
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// NewMemoryProvider stores sessions in process, for development, tests and single node.
+// Expired sessions are removed by GC, or lazily on Read.
+func NewMemoryProvider(config MemoryConfig) (prov SessionProvider, err error) {
+	cache, err := caches.NewMemoryProvider(caches.MemoryConfig{
+		MaxEntries: config.MaxEntries,
+		MaxBytes:   config.MaxBytes,
+	})
+	if err != nil {
+		return
+	}
+	prov = newSessProvide(config.Config, cache, &bsonConverter{})
+	return
+}
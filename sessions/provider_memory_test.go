@@ -0,0 +1,68 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MemoryProviderFlush(t *testing.T) {
+	provider, err := NewMemoryProvider(MemoryConfig{Config: config})
+	assert.NoError(t, err)
+
+	sid := CreateSid()
+	sess, _ := provider.Create(sid)
+	assert.NoError(t, sess.Flush())
+	_, err = provider.Read(sid)
+	assert.Equal(t, ErrNotFoundSession, err, "unchanged session is not saved")
+
+	sess, _ = provider.Create(sid, map[string]interface{}{"name": "a"})
+	assert.NoError(t, sess.Flush())
+
+	a, err := provider.Read(sid)
+	assert.NoError(t, err)
+	b, _ := provider.Read(sid)
+	b.Set("name", "b")
+	assert.NoError(t, b.Flush())
+
+	// a has no changes, does not overwrite b
+	assert.NoError(t, a.Flush())
+	sess, _ = provider.Read(sid)
+	assert.Equal(t, "b", sess.Get("name").String())
+
+	a.Delete("name")
+	assert.NoError(t, a.Flush())
+	sess, _ = provider.Read(sid)
+	assert.False(t, sess.Has("name"))
+
+	// destroyed session is not revived
+	sess.Set("name", "c")
+	assert.NoError(t, sess.Destroy())
+	assert.NoError(t, sess.Flush())
+	_, err = provider.Read(sid)
+	assert.Equal(t, ErrNotFoundSession, err)
+}
+
+func Test_MemoryProviderExpire(t *testing.T) {
+	provider, err := NewMemoryProvider(MemoryConfig{Config: Config{SessionExpire: 1, SecretKey: "secret_key"}})
+	assert.NoError(t, err)
+
+	sid := CreateSid()
+	sess, _ := provider.Create(sid, map[string]interface{}{"name": "a"})
+	assert.NoError(t, sess.Flush())
+
+	nsid := CreateSid()
+	sess, err = provider.Regenerate(sid, nsid)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", sess.Get("name").String())
+	_, err = provider.Read(sid)
+	assert.Equal(t, ErrNotFoundSession, err)
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.NoError(t, provider.GC())
+	_, err = provider.Read(nsid)
+	assert.Equal(t, ErrNotFoundSession, err)
+	_, err = provider.Regenerate(nsid, CreateSid())
+	assert.Equal(t, ErrNotFoundSession, err)
+}
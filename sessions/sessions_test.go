@@ -79,7 +79,19 @@ func initRedisProvider() {
 	manager = NewSessionManager(provider)
 }
 
+func initMemoryProvider() {
+	provider, err := NewMemoryProvider(MemoryConfig{Config: config})
+	if err != nil {
+		log.Fatal(err)
+	}
+	manager = NewSessionManager(provider)
+}
+
 func TestMain(m *testing.M) {
+	initMemoryProvider()
+	if code := m.Run(); code != 0 {
+		os.Exit(code)
+	}
 	initMgoProvider()
 	if code := m.Run(); code != 0 {
 		os.Exit(code)
@@ -0,0 +1,165 @@
+package sse
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wujiu2020/strip"
+)
+
+const (
+	HeaderLastEventId  = "Last-Event-ID"
+	HeaderContentType  = "Content-Type"
+	HeaderCacheControl = "Cache-Control"
+	HeaderConnection   = "Connection"
+	HeaderAccelBuffer  = "X-Accel-Buffering"
+
+	ContentType = "text/event-stream; charset=utf-8"
+)
+
+var (
+	// interval of comment lines keeping the connection alive
+	DefaultHeartbeat = 15 * time.Second
+)
+
+// Event is a single message of the event stream
+type Event struct {
+	Id    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+func (e *Event) encode(buf *bytes.Buffer) {
+	if e.Id != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(cleanLine(e.Id))
+		buf.WriteByte('\n')
+	}
+	if e.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(cleanLine(e.Event))
+		buf.WriteByte('\n')
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatInt(int64(e.Retry/time.Millisecond), 10))
+		buf.WriteByte('\n')
+	}
+
+	// every line of data need its own field
+	data := strings.Replace(e.Data, "\r\n", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+}
+
+// Stream is an ActionResult write events from channel until
+// the channel closed or the request context cancelled
+// eg:
+// Action() *sse.Stream
+// Action() (*sse.Stream, int)
+type Stream struct {
+	Events <-chan Event
+
+	// zero use DefaultHeartbeat, negative disable heartbeat
+	Heartbeat time.Duration
+
+	// called with the Last-Event-ID of a reconnected client,
+	// returned events are written before Events
+	Resume func(lastEventId string) []Event
+}
+
+var _ strip.ActionResult = new(Stream)
+
+func New(events <-chan Event) *Stream {
+	return &Stream{Events: events}
+}
+
+func (s *Stream) Write(ctx strip.Context, rw http.ResponseWriter, req *http.Request) {
+	headers := rw.Header()
+	headers.Set(HeaderContentType, ContentType)
+	headers.Set(HeaderCacheControl, "no-cache")
+	headers.Set(HeaderConnection, "keep-alive")
+	headers.Set(HeaderAccelBuffer, "no")
+
+	flusher, _ := rw.(http.Flusher)
+
+	var buf bytes.Buffer
+	write := func() bool {
+		_, err := rw.Write(buf.Bytes())
+		buf.Reset()
+		if err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	// flush header to client at once
+	buf.WriteString(":ok\n\n")
+	if !write() {
+		return
+	}
+
+	if lastId := LastEventId(req); lastId != "" && s.Resume != nil {
+		for _, e := range s.Resume(lastId) {
+			e.encode(&buf)
+		}
+		if buf.Len() > 0 && !write() {
+			return
+		}
+	}
+
+	interval := s.Heartbeat
+	if interval == 0 {
+		interval = DefaultHeartbeat
+	}
+
+	var heartbeat <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	done := req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case <-heartbeat:
+			buf.WriteString(":\n\n")
+		case e, ok := <-s.Events:
+			if !ok {
+				return
+			}
+			e.encode(&buf)
+		}
+		if !write() {
+			return
+		}
+	}
+}
+
+// LastEventId returns the id of last received event for a reconnected client
+func LastEventId(req *http.Request) string {
+	id := req.Header.Get(HeaderLastEventId)
+	if id == "" {
+		// EventSource polyfills can not set header
+		id = req.URL.Query().Get("lastEventId")
+	}
+	return strings.TrimSpace(id)
+}
+
+func cleanLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,115 @@
+package sse
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip"
+)
+
+func readEvents(t *testing.T, res *http.Response, n int) []string {
+	var (
+		lines  []string
+		events []string
+	)
+	scanner := bufio.NewScanner(res.Body)
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			lines = append(lines, line)
+			continue
+		}
+		events = append(events, strings.Join(lines, "\n"))
+		lines = nil
+	}
+	assert.NoError(t, scanner.Err())
+	return events
+}
+
+func Test_Stream(t *testing.T) {
+	sp := strip.New()
+	sp.Filter(strip.GenericOutFilter())
+	sp.Routers(
+		strip.Router("/events", strip.Get(func(req *http.Request) *Stream {
+			events := make(chan Event, 3)
+			events <- Event{Id: "1", Event: "progress", Data: "10"}
+			events <- Event{Id: "2", Data: "line1\nline2", Retry: 3 * time.Second}
+			close(events)
+
+			stream := New(events)
+			stream.Resume = func(lastEventId string) []Event {
+				return []Event{{Id: lastEventId + "+", Data: "missed"}}
+			}
+			return stream
+		})),
+	)
+
+	server := httptest.NewServer(sp)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer res.Body.Close()
+
+	assert.Equal(t, ContentType, res.Header.Get(HeaderContentType))
+	assert.Equal(t, "no-cache", res.Header.Get(HeaderCacheControl))
+
+	events := readEvents(t, res, 3)
+	assert.Equal(t, []string{
+		":ok",
+		"id: 1\nevent: progress\ndata: 10",
+		"id: 2\nretry: 3000\ndata: line1\ndata: line2",
+	}, events)
+
+	req, _ := http.NewRequest("GET", server.URL+"/events", nil)
+	req.Header.Set(HeaderLastEventId, "9")
+	res, err = http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer res.Body.Close()
+
+	events = readEvents(t, res, 2)
+	assert.Equal(t, []string{":ok", "id: 9+\ndata: missed"}, events)
+}
+
+func Test_StreamHeartbeatAndCancel(t *testing.T) {
+	finished := make(chan bool, 1)
+
+	sp := strip.New()
+	sp.Filter(strip.GenericOutFilter())
+	sp.Routers(
+		strip.Router("/events", strip.Get(func(ctx strip.Context, rw http.ResponseWriter, req *http.Request) {
+			stream := &Stream{Events: make(chan Event), Heartbeat: 10 * time.Millisecond}
+			stream.Write(ctx, rw, req)
+			finished <- true
+		})),
+	)
+
+	server := httptest.NewServer(sp)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	events := readEvents(t, res, 3)
+	assert.Equal(t, []string{":ok", ":", ":"}, events)
+
+	// client gone, stream should end
+	res.Body.Close()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Error("stream not finished after client closed")
+	}
+}
@@ -205,6 +205,43 @@ func Test_RouteInfo(t *testing.T) {
 	assert.True(info.Get("name") == "slene")
 }
 
+func Test_Routes(t *testing.T) {
+	assert := &Assert{T: t}
+
+	sp := New().Routers(
+		Get(nopFunc),
+
+		Router("/user",
+			Get(nopFunc),
+			Post(nopFunc),
+
+			Router("/:uid/name/:name",
+				Put(TestStruct{}).Action("Param"),
+			),
+
+			Router("/:uid/*:splat",
+				Any(&TestAllAnyStruct{}),
+			),
+		),
+	)
+
+	routes := sp.Routes()
+	assert.True(len(routes) == 5)
+
+	assert.True(routes[0].Method == "GET" && routes[0].Path == "/")
+	assert.True(routes[1].Method == "GET" && routes[1].Path == "/user")
+	assert.True(routes[2].Method == "POST" && routes[2].Path == "/user")
+
+	assert.True(routes[3].Method == "PUT" && routes[3].Path == "/user/:uid/name/:name")
+	assert.True(strings.Join(routes[3].Params, ",") == "uid,name")
+	assert.True(routes[3].Action == "Param")
+	assert.True(routes[3].Func.NumIn() == 2)
+
+	assert.True(routes[4].Method == "**" && routes[4].Path == "/user/:uid/*:splat")
+	assert.True(strings.Join(routes[4].Params, ",") == "uid,splat")
+	assert.True(routes[4].Action == "Any")
+}
+
 func Test_RouteWild(t *testing.T) {
 	assert := &Assert{T: t}
 
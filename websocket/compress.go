@@ -0,0 +1,85 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	extensionDeflate = "permessage-deflate"
+
+	// no context takeover keeps both sides stateless between messages
+	extensionDeflateResponse = "permessage-deflate; server_no_context_takeover; client_no_context_takeover"
+
+	// sync flush marker and an empty final block, RFC 7692 section 7.2.2
+	deflateTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+)
+
+var errMessageTooBig = errors.New("websocket: message too big")
+
+func compressData(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = fw.Write(p); err != nil {
+		return nil, err
+	}
+	if err = fw.Flush(); err != nil {
+		return nil, err
+	}
+
+	// remove the trailing sync flush marker, RFC 7692 section 7.2.1
+	b := buf.Bytes()
+	if len(b) >= 4 {
+		b = b[:len(b)-4]
+	}
+	return b, nil
+}
+
+func decompressData(p []byte, limit int64) ([]byte, error) {
+	fr := flate.NewReader(io.MultiReader(bytes.NewReader(p), strings.NewReader(deflateTail)))
+	defer fr.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(fr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, errMessageTooBig
+	}
+	return b, nil
+}
+
+// parse Sec-WebSocket-Extensions, return whether an acceptable permessage-deflate offered
+func offerDeflate(values []string) bool {
+	for _, value := range values {
+	offers:
+		for _, offer := range strings.Split(value, ",") {
+			params := strings.Split(offer, ";")
+			if strings.TrimSpace(params[0]) != extensionDeflate {
+				continue
+			}
+			for _, param := range params[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				switch strings.TrimSpace(kv[0]) {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					// flate always use 32K window
+					if len(kv) == 2 && strings.Trim(strings.TrimSpace(kv[1]), `"`) != "15" {
+						continue offers
+					}
+				default:
+					continue offers
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,469 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	finalBit = 1 << 7
+	rsv1Bit  = 1 << 6
+	rsv2Bit  = 1 << 5
+	rsv3Bit  = 1 << 4
+	maskBit  = 1 << 7
+
+	maxControlPayload = 125
+)
+
+type frameHeader struct {
+	fin    bool
+	rsv1   bool
+	opcode int
+	length int64
+	masked bool
+	mask   [4]byte
+}
+
+// Conn is a websocket connection, one goroutine can read and another
+// one can write at the same time
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	subprotocol string
+	compress    bool
+
+	readLimit    int64
+	fragmentSize int
+
+	// serialize writes of frames
+	wmu       sync.Mutex
+	closeSent bool
+
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool, opt Option) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{
+		conn:         conn,
+		br:           br,
+		isServer:     isServer,
+		readLimit:    opt.ReadLimit,
+		fragmentSize: opt.FragmentSize,
+	}
+	if c.readLimit <= 0 {
+		c.readLimit = DefaultReadLimit
+	}
+	c.pingHandler = func(data []byte) error {
+		err := c.WriteControl(PongMessage, data)
+		if err == ErrCloseSent {
+			err = nil
+		}
+		return err
+	}
+	c.pongHandler = func(data []byte) error {
+		return nil
+	}
+	return c
+}
+
+// Subprotocol returns the negotiated protocol
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed reports whether permessage-deflate is negotiated
+func (c *Conn) Compressed() bool {
+	return c.compress
+}
+
+func (c *Conn) SetPingHandler(h func(data []byte) error) {
+	c.pingHandler = h
+}
+
+func (c *Conn) SetPongHandler(h func(data []byte) error) {
+	c.pongHandler = h
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage reads a complete message, fragmented frames are joined,
+// ping and pong frames are passed to handlers during reading.
+// A *CloseError returned when peer closed the connection.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	var compressed bool
+
+	for {
+		var (
+			h       frameHeader
+			payload []byte
+		)
+		h, err = c.readHeader()
+		if err != nil {
+			return
+		}
+
+		if h.opcode == continuationFrame || h.opcode == TextMessage || h.opcode == BinaryMessage {
+			if int64(len(p))+h.length > c.readLimit {
+				err = c.fail(CloseMessageTooBig, "message too big")
+				return
+			}
+		}
+
+		payload, err = c.readPayload(h)
+		if err != nil {
+			return
+		}
+
+		switch h.opcode {
+		case PingMessage:
+			if err = c.pingHandler(payload); err != nil {
+				return
+			}
+			continue
+
+		case PongMessage:
+			if err = c.pongHandler(payload); err != nil {
+				return
+			}
+			continue
+
+		case CloseMessage:
+			err = c.handleClose(payload)
+			return
+
+		case continuationFrame:
+			if messageType == 0 {
+				err = c.fail(CloseProtocolError, "unexpected continuation frame")
+				return
+			}
+
+		default:
+			if messageType != 0 {
+				err = c.fail(CloseProtocolError, "expect continuation frame")
+				return
+			}
+			messageType = h.opcode
+			compressed = h.rsv1
+		}
+
+		p = append(p, payload...)
+		if h.fin {
+			break
+		}
+	}
+
+	if compressed {
+		p, err = decompressData(p, c.readLimit)
+		if err == errMessageTooBig {
+			err = c.fail(CloseMessageTooBig, "message too big")
+		} else if err != nil {
+			err = c.fail(CloseInvalidFramePayloadData, "invalid compressed data")
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if messageType == TextMessage && !utf8.Valid(p) {
+		err = c.fail(CloseInvalidFramePayloadData, "invalid utf8 text")
+		return
+	}
+	return
+}
+
+// WriteMessage writes a text or binary message, control message is sent by WriteControl
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return c.WriteControl(messageType, data)
+	}
+
+	compressed := false
+	if c.compress {
+		var err error
+		data, err = compressData(data)
+		if err != nil {
+			return err
+		}
+		compressed = true
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	size := c.fragmentSize
+	if size <= 0 || size > len(data) {
+		size = len(data)
+	}
+
+	opcode := messageType
+	for {
+		n := size
+		if n > len(data) {
+			n = len(data)
+		}
+		fin := n == len(data)
+		if err := c.writeFrame(fin, compressed && opcode != continuationFrame, opcode, data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+		opcode = continuationFrame
+		if fin {
+			return nil
+		}
+	}
+}
+
+// WriteControl writes a ping, pong or close frame
+func (c *Conn) WriteControl(messageType int, data []byte) error {
+	switch messageType {
+	case PingMessage, PongMessage, CloseMessage:
+	default:
+		return &CloseError{Code: CloseProtocolError, Text: "invalid control message type"}
+	}
+	if len(data) > maxControlPayload {
+		return &CloseError{Code: CloseProtocolError, Text: "control frame too big"}
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	return c.writeFrame(true, false, messageType, data)
+}
+
+// CloseWith sends close frame with code and reason, then close the connection
+func (c *Conn) CloseWith(code int, text string) error {
+	err := c.WriteControl(CloseMessage, FormatCloseMessage(code, text))
+	if err == ErrCloseSent {
+		err = nil
+	}
+	if er := c.conn.Close(); err == nil {
+		err = er
+	}
+	return err
+}
+
+// Close sends a normal closure frame if not sent yet, and close the connection
+func (c *Conn) Close() error {
+	return c.CloseWith(CloseNormalClosure, "")
+}
+
+// FormatCloseMessage formats payload of a close frame
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+func (c *Conn) handleClose(payload []byte) error {
+	code := CloseNoStatusReceived
+	text := ""
+	if len(payload) == 1 {
+		return c.fail(CloseProtocolError, "invalid close payload")
+	}
+	if len(payload) >= 2 {
+		code = int(binary.BigEndian.Uint16(payload))
+		text = string(payload[2:])
+		if !validCloseCode(code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(text) {
+			return c.fail(CloseInvalidFramePayloadData, "invalid utf8 close reason")
+		}
+	}
+
+	// echo the close code
+	err := c.WriteControl(CloseMessage, FormatCloseMessage(code, ""))
+	if err != nil && err != ErrCloseSent {
+		return err
+	}
+	return &CloseError{Code: code, Text: text}
+}
+
+// fail the connection with close code
+func (c *Conn) fail(code int, text string) error {
+	c.WriteControl(CloseMessage, FormatCloseMessage(code, text))
+	c.conn.Close()
+	return &CloseError{Code: code, Text: text}
+}
+
+func (c *Conn) readHeader() (h frameHeader, err error) {
+	var b [8]byte
+	if _, err = io.ReadFull(c.br, b[:2]); err != nil {
+		return
+	}
+
+	h.fin = b[0]&finalBit != 0
+	h.rsv1 = b[0]&rsv1Bit != 0
+	h.opcode = int(b[0] & 0xf)
+	h.masked = b[1]&maskBit != 0
+	h.length = int64(b[1] & 0x7f)
+
+	if b[0]&(rsv2Bit|rsv3Bit) != 0 {
+		err = c.fail(CloseProtocolError, "unexpected reserved bits")
+		return
+	}
+
+	switch h.opcode {
+	case continuationFrame:
+		if h.rsv1 {
+			err = c.fail(CloseProtocolError, "unexpected reserved bits")
+			return
+		}
+	case TextMessage, BinaryMessage:
+		if h.rsv1 && !c.compress {
+			err = c.fail(CloseProtocolError, "unexpected reserved bits")
+			return
+		}
+	case CloseMessage, PingMessage, PongMessage:
+		if h.rsv1 || !h.fin || h.length > maxControlPayload {
+			err = c.fail(CloseProtocolError, "invalid control frame")
+			return
+		}
+	default:
+		err = c.fail(CloseProtocolError, "unknown opcode")
+		return
+	}
+
+	if h.masked != c.isServer {
+		err = c.fail(CloseProtocolError, "incorrect mask flag")
+		return
+	}
+
+	switch h.length {
+	case 126:
+		if _, err = io.ReadFull(c.br, b[:2]); err != nil {
+			return
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err = io.ReadFull(c.br, b[:8]); err != nil {
+			return
+		}
+		h.length = int64(binary.BigEndian.Uint64(b[:8]))
+		if h.length < 0 {
+			err = c.fail(CloseProtocolError, "invalid frame length")
+			return
+		}
+	}
+
+	if h.masked {
+		if _, err = io.ReadFull(c.br, h.mask[:]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *Conn) readPayload(h frameHeader) (payload []byte, err error) {
+	payload = make([]byte, h.length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if h.masked {
+		maskBytes(h.mask, payload)
+	}
+	return
+}
+
+func (c *Conn) writeFrame(fin, rsv1 bool, opcode int, payload []byte) error {
+	buf := make([]byte, 0, 14+len(payload))
+
+	b0 := byte(opcode)
+	if fin {
+		b0 |= finalBit
+	}
+	if rsv1 {
+		b0 |= rsv1Bit
+	}
+	buf = append(buf, b0)
+
+	var b1 byte
+	if !c.isServer {
+		b1 |= maskBit
+	}
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		buf = append(buf, b1|byte(length))
+	case length <= 0xffff:
+		buf = append(buf, b1|126, 0, 0)
+		binary.BigEndian.PutUint16(buf[len(buf)-2:], uint16(length))
+	default:
+		buf = append(buf, b1|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[len(buf)-8:], uint64(length))
+	}
+
+	if c.isServer {
+		buf = append(buf, payload...)
+	} else {
+		// client frames must be masked
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		buf = append(buf, mask[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		maskBytes(mask, buf[start:])
+	}
+
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i&3]
+	}
+}
+
+func validCloseCode(code int) bool {
+	switch code {
+	case CloseNormalClosure, CloseGoingAway, CloseProtocolError, CloseUnsupportedData,
+		CloseInvalidFramePayloadData, ClosePolicyViolation, CloseMessageTooBig,
+		CloseMandatoryExtension, CloseInternalServerErr:
+		return true
+	}
+	return code >= 3000 && code <= 4999
+}
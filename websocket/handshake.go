@@ -0,0 +1,273 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	HeaderUpgrade     = "Upgrade"
+	HeaderConnection  = "Connection"
+	HeaderOrigin      = "Origin"
+	HeaderKey         = "Sec-WebSocket-Key"
+	HeaderVersion     = "Sec-WebSocket-Version"
+	HeaderAccept      = "Sec-WebSocket-Accept"
+	HeaderProtocol    = "Sec-WebSocket-Protocol"
+	HeaderExtensions  = "Sec-WebSocket-Extensions"
+	headerKeyGUID     = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	supportedVersion  = "13"
+	handshakeUpgrade  = "websocket"
+	handshakeConnType = "Upgrade"
+)
+
+// Upgrade does the server side handshake then take over the connection
+func Upgrade(rw http.ResponseWriter, req *http.Request, opts ...Option) (conn *Conn, err error) {
+	var opt Option
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	status := http.StatusBadRequest
+	defer func() {
+		if err != nil && status != 0 {
+			rw.Header().Set(HeaderVersion, supportedVersion)
+			http.Error(rw, http.StatusText(status), status)
+		}
+	}()
+
+	if req.Method != "GET" {
+		status = http.StatusMethodNotAllowed
+		err = fmt.Errorf("%v: method must be GET", ErrBadHandshake)
+		return
+	}
+	if !headerContains(req.Header, HeaderConnection, "upgrade") ||
+		!headerContains(req.Header, HeaderUpgrade, handshakeUpgrade) {
+		status = http.StatusUpgradeRequired
+		err = fmt.Errorf("%v: not a websocket upgrade request", ErrBadHandshake)
+		return
+	}
+	if req.Header.Get(HeaderVersion) != supportedVersion {
+		status = http.StatusUpgradeRequired
+		err = fmt.Errorf("%v: unsupported version", ErrBadHandshake)
+		return
+	}
+
+	checkOrigin := opt.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(req) {
+		status = http.StatusForbidden
+		err = ErrBadOrigin
+		return
+	}
+
+	key := strings.TrimSpace(req.Header.Get(HeaderKey))
+	if b, er := base64.StdEncoding.DecodeString(key); er != nil || len(b) != 16 {
+		err = fmt.Errorf("%v: invalid %s", ErrBadHandshake, HeaderKey)
+		return
+	}
+
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		status = http.StatusInternalServerError
+		err = fmt.Errorf("the ResponseWriter doesn't support the Hijacker interface")
+		return
+	}
+
+	subprotocol := selectSubprotocol(req, opt.Subprotocols)
+	compress := opt.EnableCompression && offerDeflate(req.Header[http.CanonicalHeaderKey(HeaderExtensions)])
+
+	// keep headers set by filters, eg: X-Reqid
+	header := make(http.Header)
+	for k, v := range rw.Header() {
+		header[k] = v
+	}
+	header.Set(HeaderUpgrade, handshakeUpgrade)
+	header.Set(HeaderConnection, handshakeConnType)
+	header.Set(HeaderAccept, acceptKey(key))
+	if subprotocol != "" {
+		header.Set(HeaderProtocol, subprotocol)
+	}
+	if compress {
+		header.Set(HeaderExtensions, extensionDeflateResponse)
+	}
+
+	netConn, brw, err := hijacker.Hijack()
+	if err != nil {
+		status = 0
+		return
+	}
+	status = 0
+
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+	if _, err = netConn.Write(buf.Bytes()); err != nil {
+		netConn.Close()
+		return
+	}
+
+	conn = newConn(netConn, brw.Reader, true, opt)
+	conn.subprotocol = subprotocol
+	conn.compress = compress
+	return
+}
+
+// Dial does the client side handshake, mostly used for testing
+func Dial(rawurl string, header http.Header, opts ...Option) (conn *Conn, res *http.Response, err error) {
+	var opt Option
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return
+	}
+
+	useTLS := false
+	switch u.Scheme {
+	case "ws", "http":
+	case "wss", "https":
+		useTLS = true
+	default:
+		err = fmt.Errorf("websocket: bad scheme `%s`", u.Scheme)
+		return
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		if useTLS {
+			host += ":443"
+		} else {
+			host += ":80"
+		}
+	}
+
+	var netConn net.Conn
+	if useTLS {
+		netConn, err = tls.Dial("tcp", host, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		netConn, err = net.Dial("tcp", host)
+	}
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			netConn.Close()
+		}
+	}()
+
+	var b [16]byte
+	if _, err = rand.Read(b[:]); err != nil {
+		return
+	}
+	key := base64.StdEncoding.EncodeToString(b[:])
+
+	req := &http.Request{
+		Method:     "GET",
+		URL:        &url.URL{Path: u.Path, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set(HeaderUpgrade, handshakeUpgrade)
+	req.Header.Set(HeaderConnection, handshakeConnType)
+	req.Header.Set(HeaderKey, key)
+	req.Header.Set(HeaderVersion, supportedVersion)
+	if len(opt.Subprotocols) > 0 {
+		req.Header.Set(HeaderProtocol, strings.Join(opt.Subprotocols, ", "))
+	}
+	if opt.EnableCompression {
+		req.Header.Set(HeaderExtensions, "permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+	}
+
+	if err = req.Write(netConn); err != nil {
+		return
+	}
+
+	br := bufio.NewReader(netConn)
+	res, err = http.ReadResponse(br, req)
+	if err != nil {
+		return
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContains(res.Header, HeaderUpgrade, handshakeUpgrade) ||
+		!headerContains(res.Header, HeaderConnection, "upgrade") ||
+		res.Header.Get(HeaderAccept) != acceptKey(key) {
+		err = ErrBadHandshake
+		return
+	}
+
+	conn = newConn(netConn, br, false, opt)
+	conn.subprotocol = res.Header.Get(HeaderProtocol)
+	conn.compress = strings.HasPrefix(strings.TrimSpace(res.Header.Get(HeaderExtensions)), extensionDeflate)
+	return
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + headerKeyGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func sameOrigin(req *http.Request) bool {
+	origin := req.Header.Get(HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, req.Host)
+}
+
+// selectSubprotocol returns the first of server supported ones offered by client
+func selectSubprotocol(req *http.Request, supported []string) string {
+	offered := make(map[string]bool)
+	for _, value := range req.Header[http.CanonicalHeaderKey(HeaderProtocol)] {
+		for _, p := range strings.Split(value, ",") {
+			offered[strings.TrimSpace(p)] = true
+		}
+	}
+	for _, s := range supported {
+		if offered[s] {
+			return s
+		}
+	}
+	return ""
+}
+
+// check comma separated header values contains token, case insensitive
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/wujiu2020/strip"
+)
+
+// Message types defined in RFC 6455, section 11.8
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// Close codes defined in RFC 6455, section 11.7
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+var (
+	DefaultReadLimit int64 = 32 << 20 /* 32 MB */
+
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	ErrCloseSent    = errors.New("websocket: close sent")
+	ErrBadOrigin    = errors.New("websocket: request origin not allowed")
+)
+
+// CloseError is returned by read methods when close frame received,
+// or the connection failed by protocol error
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsCloseError reports whether err is a *CloseError with one of the codes
+func IsCloseError(err error, codes ...int) bool {
+	e, ok := err.(*CloseError)
+	if !ok {
+		return false
+	}
+	if len(codes) == 0 {
+		return true
+	}
+	for _, code := range codes {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+type Option struct {
+	// server side subprotocols in order of preference
+	Subprotocols []string
+
+	// nil only allows same origin request
+	CheckOrigin func(req *http.Request) bool
+
+	// negotiate permessage-deflate (RFC 7692) with peer
+	EnableCompression bool
+
+	// max size of a message, zero use DefaultReadLimit
+	ReadLimit int64
+
+	// split written messages into frames of this size, zero means no fragmentation
+	FragmentSize int
+}
+
+// Action returns a route action which upgrade the connection then invoke
+// the handler with injected dependencies, the *Conn is provided in context
+// eg:
+// strip.Get(websocket.Action(func(conn *websocket.Conn, log strip.Logger) {}))
+func Action(handler interface{}, opts ...Option) interface{} {
+	return func(ctx strip.Context, rw http.ResponseWriter, req *http.Request, log strip.Logger) {
+		conn, err := Upgrade(rw, req, opts...)
+		if err != nil {
+			log.Info("websocket upgrade failed:", err)
+			return
+		}
+		defer conn.Close()
+
+		ctx.Provide(conn)
+
+		_, err = ctx.Invoke(handler)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wujiu2020/strip"
+)
+
+type echoService struct {
+	prefix string
+}
+
+func newEchoServer(opt Option) *httptest.Server {
+	sp := strip.New()
+	sp.Provide(&echoService{prefix: "echo:"})
+	sp.Routers(
+		strip.Router("/ws", strip.Get(Action(func(conn *Conn, service *echoService, log strip.Logger) {
+			for {
+				typ, msg, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				if typ == TextMessage {
+					msg = append([]byte(service.prefix), msg...)
+				}
+				if err = conn.WriteMessage(typ, msg); err != nil {
+					log.Error(err)
+					return
+				}
+			}
+		}, opt))),
+	)
+	return httptest.NewServer(sp)
+}
+
+func wsURL(server *httptest.Server, path string) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + path
+}
+
+func Test_Echo(t *testing.T) {
+	server := newEchoServer(Option{Subprotocols: []string{"chat"}})
+	defer server.Close()
+
+	conn, res, err := Dial(wsURL(server, "/ws"), nil, Option{Subprotocols: []string{"other", "chat"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal(t, "chat", conn.Subprotocol())
+	assert.False(t, conn.Compressed())
+
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte("hello")))
+	typ, msg, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, TextMessage, typ)
+	assert.Equal(t, "echo:hello", string(msg))
+
+	big := bytes.Repeat([]byte{1, 2, 3}, 70000)
+	assert.NoError(t, conn.WriteMessage(BinaryMessage, big))
+	typ, msg, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, BinaryMessage, typ)
+	assert.Equal(t, big, msg)
+
+	// pong answered while reading
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data []byte) error {
+		pong <- string(data)
+		return nil
+	})
+	assert.NoError(t, conn.WriteControl(PingMessage, []byte("ping")))
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte("after ping")))
+	_, msg, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "echo:after ping", string(msg))
+	assert.Equal(t, "ping", <-pong)
+
+	// close handshake
+	assert.NoError(t, conn.WriteControl(CloseMessage, FormatCloseMessage(CloseGoingAway, "bye")))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, IsCloseError(err, CloseGoingAway))
+}
+
+func Test_FragmentAndCompression(t *testing.T) {
+	opt := Option{EnableCompression: true, FragmentSize: 16}
+	server := newEchoServer(opt)
+	defer server.Close()
+
+	conn, _, err := Dial(wsURL(server, "/ws"), nil, opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	assert.True(t, conn.Compressed())
+
+	text := strings.Repeat("fragmented and compressed ", 100)
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte(text)))
+	typ, msg, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, TextMessage, typ)
+	assert.Equal(t, "echo:"+text, string(msg))
+}
+
+func Test_ReadLimit(t *testing.T) {
+	server := newEchoServer(Option{ReadLimit: 10})
+	defer server.Close()
+
+	conn, _, err := Dial(wsURL(server, "/ws"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	assert.NoError(t, conn.WriteMessage(TextMessage, []byte("more than ten bytes")))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, IsCloseError(err, CloseMessageTooBig))
+}
+
+func Test_BadHandshake(t *testing.T) {
+	server := newEchoServer(Option{})
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/ws")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusUpgradeRequired, res.StatusCode)
+	}
+
+	_, _, err = Dial(wsURL(server, "/ws"), http.Header{HeaderOrigin: {"http://evil.com"}})
+	assert.Equal(t, ErrBadHandshake, err)
+
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func Test_SelectSubprotocol(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/ws", nil)
+	req.Header.Add(HeaderProtocol, "v1, v2")
+	req.Header.Add(HeaderProtocol, "v3")
+
+	assert.Equal(t, "v2", selectSubprotocol(req, []string{"v2", "v1"}), "server preference")
+	assert.Equal(t, "v3", selectSubprotocol(req, []string{"v4", "v3"}))
+	assert.Equal(t, "", selectSubprotocol(req, []string{"v4"}))
+}